@@ -35,10 +35,11 @@ func TestMigration_FindAll(t *testing.T) {
 
 // TestMigration_FindByID shows a case where GORM's First() generates different query.
 // xo:   SELECT ... WHERE id = ?
-// GORM: SELECT * FROM `products` WHERE `products`.`id` = ? ORDER BY `products`.`id` LIMIT 1
+// GORM: SELECT * FROM `products` WHERE `products`.`id` = ? ORDER BY `products`.`id` LIMIT ?
 //
 // GORM's First() adds ORDER BY and LIMIT, which is semantically different.
-// This test demonstrates detecting such differences.
+// The difference was reviewed and accepted: id is the primary key, so at most
+// one row matches. If either query changes, the test fails again.
 func TestMigration_FindByID(t *testing.T) {
 	t.Parallel()
 
@@ -56,16 +57,11 @@ func TestMigration_FindByID(t *testing.T) {
 		repo.FindByID(context.Background(), 1)
 	})
 
-	// These queries are intentionally different - GORM adds ORDER BY and LIMIT
-	// This test shows the difference detection working correctly
-	expected := m.ExpectedQueries()
-	actual := m.ActualQueries()
-
-	t.Logf("Expected: %s", expected[0].Normalized)
-	t.Logf("Actual:   %s", actual[0].Normalized)
-
-	// Note: In a real migration, you might need to adjust the GORM query
-	// to use .Take() instead of .First() for equivalent behavior
+	m.AssertWithOptions(t, migratiorm.AcceptDifference(
+		"SELECT * FROM products WHERE id = ?",
+		"SELECT * FROM products WHERE id = ? ORDER BY id LIMIT ?",
+		"First() orders by the primary key, which matches at most one row",
+	))
 }
 
 // TestMigration_FindByCategory verifies FindByCategory with semantic comparison.
@@ -0,0 +1,86 @@
+package migratiorm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ucpr/migratiorm/internal/comparator"
+)
+
+// AcceptedDifference is a difference between an expected and an actual query
+// that was reviewed and accepted. Queries are compared after normalization.
+type AcceptedDifference struct {
+	Expected string `json:"expected"` // Expected query, "" for an accepted EXTRA query
+	Actual   string `json:"actual"`   // Actual query, "" for an accepted MISSING query
+	Reason   string `json:"reason"`   // Why the difference is acceptable
+}
+
+// acceptedFile is the on-disk representation of accepted differences.
+type acceptedFile struct {
+	Accepted []AcceptedDifference `json:"accepted"`
+}
+
+// AcceptDifference accepts the MODIFIED difference from expected to actual,
+// e.g. GORM's First() adding ORDER BY id LIMIT 1. Use "" as expected for an
+// EXTRA query and "" as actual for a MISSING query.
+//
+// An accepted difference passes the assertion and is listed as ACCEPTED with
+// its reason. If either query changes, the difference no longer matches and
+// fails again.
+func AcceptDifference(expected, actual, reason string) AssertOption {
+	return func(o *assertOptions) {
+		o.accepted = append(o.accepted, AcceptedDifference{Expected: expected, Actual: actual, Reason: reason})
+	}
+}
+
+// AcceptDifferencesFile accepts the differences listed in a checked-in JSON file,
+// conventionally testdata/<name>.accepted.json:
+//
+//	{"accepted": [{"expected": "...", "actual": "...", "reason": "..."}]}
+func AcceptDifferencesFile(path string) AssertOption {
+	return func(o *assertOptions) {
+		o.acceptedFiles = append(o.acceptedFiles, path)
+	}
+}
+
+// readAccepted reads accepted differences from a file.
+func readAccepted(path string) ([]AcceptedDifference, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file acceptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Accepted, nil
+}
+
+// acceptedDifferences returns the accepted differences of an assertion with
+// normalized queries.
+func (m *Migratiorm) acceptedDifferences(opts assertOptions) ([]comparator.Accepted, error) {
+	accepted := opts.accepted
+	for _, path := range opts.acceptedFiles {
+		fromFile, err := readAccepted(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read accepted differences %s: %w", path, err)
+		}
+		accepted = append(accepted, fromFile...)
+	}
+
+	result := make([]comparator.Accepted, len(accepted))
+	for i, a := range accepted {
+		if a.Reason == "" {
+			return nil, errors.New("accepted difference without a reason: " + a.Expected + " → " + a.Actual)
+		}
+		result[i] = comparator.Accepted{
+			Expected: m.normalizer.Normalize(a.Expected),
+			Actual:   m.actualNormalizer.Normalize(a.Actual),
+			Reason:   a.Reason,
+		}
+	}
+	return result, nil
+}
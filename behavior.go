@@ -0,0 +1,207 @@
+package migratiorm
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithSnapshotTables snapshots the given tables before and after Expect and
+// Actual, and reports rows that one side wrote differently from the other.
+// Snapshots require a real database (see WithDriver and WithConnector) and are
+// read on a connection whose queries are not captured. Tables are read with
+// SELECT * FROM table, so names must be trusted and quoted as the database
+// requires, e.g. `order` for MySQL.
+//
+// The rows each side inserted and deleted are compared, so Actual may run
+// against the state Expect left behind. An updated row counts as a deleted row
+// with its old values and an inserted row with its new values. Rows are
+// compared with all their columns, so values the database generates, such as
+// auto-increment keys and timestamps, differ between the sides unless they are
+// excluded with WithSnapshotIgnoreColumns.
+func WithSnapshotTables(tables ...string) Option {
+	return func(o *options) {
+		o.snapshotTables = append(o.snapshotTables, tables...)
+	}
+}
+
+// WithSnapshotIgnoreColumns excludes columns from the rows compared by
+// WithSnapshotTables. A column is named as column for all snapshot tables, or
+// as table.column for a single one. Names are matched case-insensitively.
+func WithSnapshotIgnoreColumns(columns ...string) Option {
+	return func(o *options) {
+		o.snapshotIgnore = append(o.snapshotIgnore, columns...)
+	}
+}
+
+// effects holds the observable behavior of a callback besides its queries.
+type effects struct {
+	result    any                    // Value returned by the callback
+	hasResult bool                   // Whether the callback returns a value
+	changes   map[string]tableChange // Rows written per snapshot table, nil without snapshots
+}
+
+// tableChange holds the rows a callback inserted into and deleted from a table.
+type tableChange struct {
+	inserted []string
+	deleted  []string
+}
+
+// snapshotTables reads the rows of tables, formatted as strings, without the
+// ignored columns.
+func snapshotTables(db *sql.DB, tables, ignore []string) (map[string][]string, error) {
+	snapshot := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		rows, err := readTable(db, table, ignore)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+		snapshot[table] = rows
+	}
+	return snapshot, nil
+}
+
+// readTable reads all rows of a table, formatted as (column=value, ...)
+// without the ignored columns.
+func readTable(db *sql.DB, table string, ignore []string) ([]string, error) {
+	rows, err := db.Query("SELECT * FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	skip := make([]bool, len(columns))
+	for i, column := range columns {
+		skip[i] = ignoredColumn(table, column, ignore)
+	}
+
+	result := make([]string, 0)
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		result = append(result, formatRow(columns, values, skip))
+	}
+	return result, rows.Err()
+}
+
+// ignoredColumn reports whether a column of table is named in ignore, either
+// as column or as table.column.
+func ignoredColumn(table, column string, ignore []string) bool {
+	for _, name := range ignore {
+		if strings.EqualFold(name, column) || strings.EqualFold(name, table+"."+column) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRow formats a row as (column=value, ...), leaving out the columns
+// marked in skip.
+func formatRow(columns []string, values []any, skip []bool) string {
+	parts := make([]string, 0, len(columns))
+	for i, column := range columns {
+		if skip[i] {
+			continue
+		}
+		switch v := values[i].(type) {
+		case nil:
+			parts = append(parts, column+"=NULL")
+		case []byte:
+			parts = append(parts, column+"="+string(v))
+		default:
+			parts = append(parts, fmt.Sprintf("%s=%v", column, v))
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// diffTables returns the rows written to each table between two snapshots.
+func diffTables(before, after map[string][]string) map[string]tableChange {
+	changes := make(map[string]tableChange, len(after))
+	for table, rows := range after {
+		inserted, deleted := diffRows(before[table], rows)
+		changes[table] = tableChange{inserted: inserted, deleted: deleted}
+	}
+	return changes
+}
+
+// diffRows returns the rows of b missing from a and the rows of a missing
+// from b, compared as sorted multisets.
+func diffRows(a, b []string) (onlyB, onlyA []string) {
+	counts := make(map[string]int, len(a))
+	for _, row := range a {
+		counts[row]++
+	}
+	for _, row := range b {
+		if counts[row] > 0 {
+			counts[row]--
+			continue
+		}
+		onlyB = append(onlyB, row)
+	}
+	for _, row := range a {
+		if counts[row] > 0 {
+			counts[row]--
+			onlyA = append(onlyA, row)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	return onlyB, onlyA
+}
+
+// behaviorDifferences compares the callback results and the rows written by
+// Expect and Actual. It returns the formatted report, or "" when they match.
+func (m *Migratiorm) behaviorDifferences() string {
+	var sb strings.Builder
+
+	e, a := m.expectEffects, m.actualEffects
+	if e.hasResult && a.hasResult && !reflect.DeepEqual(e.result, a.result) {
+		sb.WriteString("  RESULT:\n")
+		sb.WriteString(fmt.Sprintf("      expected: %+v\n", e.result))
+		sb.WriteString(fmt.Sprintf("      actual:   %+v\n", a.result))
+	}
+
+	if e.changes != nil && a.changes != nil {
+		for _, table := range m.options.snapshotTables {
+			expected, actual := e.changes[table], a.changes[table]
+			lines := make([]string, 0)
+			onlyActual, onlyExpected := diffRows(expected.inserted, actual.inserted)
+			lines = appendRows(lines, "inserted only by Expect", onlyExpected)
+			lines = appendRows(lines, "inserted only by Actual", onlyActual)
+			onlyActual, onlyExpected = diffRows(expected.deleted, actual.deleted)
+			lines = appendRows(lines, "deleted only by Expect", onlyExpected)
+			lines = appendRows(lines, "deleted only by Actual", onlyActual)
+
+			if len(lines) > 0 {
+				sb.WriteString(fmt.Sprintf("  TABLE %s:\n", table))
+				sb.WriteString(strings.Join(lines, ""))
+			}
+		}
+	}
+
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "Behavior differences:\n" + sb.String()
+}
+
+// appendRows appends a labeled line for each row.
+func appendRows(lines []string, label string, rows []string) []string {
+	for _, row := range rows {
+		lines = append(lines, fmt.Sprintf("      %s: %s\n", label, row))
+	}
+	return lines
+}
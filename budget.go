@@ -0,0 +1,182 @@
+package migratiorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// queryBudget limits the number of Actual queries, optionally of an
+// operation type or on a table.
+type queryBudget struct {
+	op    *OperationType // Counted operation, nil for all operations
+	table string         // Counted table, "" for all tables
+	limit int
+}
+
+// MaxQueries fails the assertion when Actual issues more than n queries.
+// Ignored queries are not counted.
+func MaxQueries(n int) AssertOption {
+	return func(o *assertOptions) {
+		o.budgets = append(o.budgets, queryBudget{limit: n})
+	}
+}
+
+// MaxQueriesPerOperation fails the assertion when Actual issues more than n
+// queries of the operation type.
+func MaxQueriesPerOperation(op OperationType, n int) AssertOption {
+	return func(o *assertOptions) {
+		o.budgets = append(o.budgets, queryBudget{op: &op, limit: n})
+	}
+}
+
+// MaxQueriesPerTable fails the assertion when Actual issues more than n
+// queries that read or write table, e.g. in a FROM, JOIN or INTO clause.
+// Table names are compared case-insensitively.
+func MaxQueriesPerTable(table string, n int) AssertOption {
+	return func(o *assertOptions) {
+		o.budgets = append(o.budgets, queryBudget{table: table, limit: n})
+	}
+}
+
+// DetectNPlusOne fails the assertion when Actual issues a run of the same
+// normalized query, differing only in its bind arguments, that is longer than
+// the number of queries Expect issued of that operation on that table.
+// This catches an ORM turning one JOIN into a loop of per-row SELECTs.
+// Only consecutive runs are detected, so per-row queries interleaved with
+// other queries, e.g. one SELECT per table for each row, are not reported.
+// A run counts the distinct arguments it binds, so repeating a query with the
+// same arguments, e.g. re-reading a row, is not reported.
+func DetectNPlusOne() AssertOption {
+	return func(o *assertOptions) {
+		o.detectNPlusOne = true
+	}
+}
+
+// matches reports whether the budget counts the query.
+func (b queryBudget) matches(q Query, tables []string) bool {
+	if b.op != nil && q.Operation != *b.op {
+		return false
+	}
+	return b.table == "" || containsTable(tables, b.table)
+}
+
+// describe returns the plural noun for the counted queries.
+func (b queryBudget) describe() string {
+	noun := "queries"
+	if b.op != nil {
+		noun = b.op.String() + "s"
+	}
+	if b.table != "" {
+		noun += " on " + b.table
+	}
+	return noun
+}
+
+// countDifferences checks the query budgets and the N+1 detector.
+// It returns the formatted report, or "" when the counts are within limits.
+func (m *Migratiorm) countDifferences(expected, actual []Query, opts assertOptions) string {
+	expectedTables := m.queryTables(expected)
+	actualTables := m.queryTables(actual)
+
+	lines := make([]string, 0)
+	for _, b := range opts.budgets {
+		count := countQueries(actual, actualTables, b.matches)
+		if count > b.limit {
+			lines = append(lines, fmt.Sprintf("  Actual issued %d %s, budget is %d (Expect issued %d)",
+				count, b.describe(), b.limit, countQueries(expected, expectedTables, b.matches)))
+		}
+	}
+
+	if opts.detectNPlusOne {
+		reported := make(map[string]bool)
+		for _, run := range queryRuns(actual) {
+			q := actual[run[0]]
+			length := distinctArgs(actual[run[0]:run[1]])
+			if reported[q.Normalized] || length < 2 {
+				continue
+			}
+
+			// Expect issued the same work in queries of the same operation on the same table
+			table := ""
+			if tables := actualTables[run[0]]; len(tables) > 0 {
+				table = tables[0]
+			}
+			b := queryBudget{op: &q.Operation, table: table}
+			if count := countQueries(expected, expectedTables, b.matches); length > count {
+				reported[q.Normalized] = true
+				lines = append(lines, fmt.Sprintf("  Actual issued %d %s where Expect issued %d", length, b.describe(), count))
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Query counts:\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// queryTables returns the tables of each query.
+func (m *Migratiorm) queryTables(queries []Query) [][]string {
+	result := make([][]string, len(queries))
+	for i, q := range queries {
+		result[i] = m.normalizer.Tables(q.Normalized)
+	}
+	return result
+}
+
+// countQueries counts the queries accepted by match.
+func countQueries(queries []Query, tables [][]string, match func(q Query, tables []string) bool) int {
+	count := 0
+	for i, q := range queries {
+		if match(q, tables[i]) {
+			count++
+		}
+	}
+	return count
+}
+
+// queryRuns returns the [start, end) ranges of consecutive queries with the
+// same normalized text, for runs of at least two queries.
+func queryRuns(queries []Query) [][2]int {
+	var runs [][2]int
+	for start := 0; start < len(queries); {
+		end := start + 1
+		for end < len(queries) && queries[end].Normalized == queries[start].Normalized {
+			end++
+		}
+		if end-start > 1 {
+			runs = append(runs, [2]int{start, end})
+		}
+		start = end
+	}
+	return runs
+}
+
+// distinctArgs counts the distinct normalized arguments of queries.
+func distinctArgs(queries []Query) int {
+	count := 0
+	for i, q := range queries {
+		seen := false
+		for _, prev := range queries[:i] {
+			if reflect.DeepEqual(prev.NormalizedArgs, q.NormalizedArgs) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			count++
+		}
+	}
+	return count
+}
+
+// containsTable reports whether tables contains table, ignoring case.
+func containsTable(tables []string, table string) bool {
+	for _, t := range tables {
+		if strings.EqualFold(t, table) {
+			return true
+		}
+	}
+	return false
+}
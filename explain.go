@@ -0,0 +1,72 @@
+package migratiorm
+
+import (
+	"fmt"
+
+	"github.com/ucpr/migratiorm/internal/comparator"
+	"github.com/ucpr/migratiorm/internal/normalizer"
+)
+
+// TraceStep is a step of the normalization pipeline with the query before and after it.
+type TraceStep = normalizer.TraceStep
+
+// Explain returns the normalization steps applied to a raw query with the
+// options of m and the rewrite rules of the given side, in the order they ran.
+// It helps to find out why two queries still differ after normalization:
+//
+//	for _, s := range m.Explain(migratiorm.ActualSide, query) {
+//		t.Logf("%s: %s", s.Name, s.Output)
+//	}
+func (m *Migratiorm) Explain(side Side, query string) []TraceStep {
+	switch side {
+	case ExpectedSide:
+		return m.normalizer.Explain(query)
+	case ActualSide:
+		return m.actualNormalizer.Explain(query)
+	default:
+		return normalizer.New(m.options.sideNormalizerOptions(side)).Explain(query)
+	}
+}
+
+// WithTrace enables or disables printing the normalization trace of both
+// queries under each MODIFIED entry of an assertion failure. Steps that left
+// the query unchanged are listed without their output.
+// Expected queries read from a golden file are traced from their stored,
+// already normalized text.
+func WithTrace(enabled bool) Option {
+	return func(o *options) {
+		o.trace = enabled
+	}
+}
+
+// traceLines formats the normalization trace of a raw query, one line per step.
+func traceLines(label, raw string, trace []TraceStep) []string {
+	width := len("raw")
+	for _, s := range trace {
+		width = max(width, len(s.Name))
+	}
+
+	lines := make([]string, 0, len(trace)+2)
+	lines = append(lines, label+" trace:")
+	lines = append(lines, fmt.Sprintf("  %-*s  %s", width+1, "raw:", raw))
+	for _, s := range trace {
+		output := "(unchanged)"
+		if s.Output != s.Input {
+			output = s.Output
+		}
+		lines = append(lines, fmt.Sprintf("  %-*s  %s", width+1, s.Name+":", output))
+	}
+	return lines
+}
+
+// addTraces sets the normalization trace of each MODIFIED difference.
+func (m *Migratiorm) addTraces(differences []comparator.Difference, expected, actual []Query) {
+	for i, diff := range differences {
+		if diff.Type != comparator.DiffModified {
+			continue
+		}
+		e, a := expected[diff.ExpectedIndex].Raw, actual[diff.ActualIndex].Raw
+		lines := traceLines("expected", e, m.normalizer.Explain(e))
+		differences[i].Trace = append(lines, traceLines("actual", a, m.actualNormalizer.Explain(a))...)
+	}
+}
@@ -0,0 +1,242 @@
+package migratiorm
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/ucpr/migratiorm/internal/comparator"
+)
+
+// GoldenUpdateEnv is the environment variable that enables golden file updates.
+// Set it to a true value (1, true, ...) to rewrite golden files from Expect.
+const GoldenUpdateEnv = "MIGRATIORM_UPDATE_GOLDEN"
+
+// WithGoldenFile enables snapshot mode using the golden file at path,
+// conventionally testdata/<name>.golden.
+//
+// In update mode (see WithUpdateGolden and GoldenUpdateEnv), Assert writes the
+// queries captured by Expect to the file. If Expect was not called, Assert
+// fails without touching the file, so an existing baseline is never replaced
+// by zero queries. Otherwise Assert compares Actual against the queries stored
+// in the file, so the legacy implementation is no longer needed and Expect
+// does not have to be called.
+func WithGoldenFile(path string) Option {
+	return func(o *options) {
+		o.goldenFile = path
+	}
+}
+
+// WithUpdateGolden forces golden file updates on or off, overriding GoldenUpdateEnv.
+func WithUpdateGolden(enabled bool) Option {
+	return func(o *options) {
+		o.updateGolden = &enabled
+	}
+}
+
+// updatingGolden reports whether golden files should be rewritten.
+func (o options) updatingGolden() bool {
+	if o.updateGolden != nil {
+		return *o.updateGolden
+	}
+	update, _ := strconv.ParseBool(os.Getenv(GoldenUpdateEnv))
+	return update
+}
+
+// goldenFile is the on-disk representation of expected queries.
+type goldenFile struct {
+	Queries []goldenQuery `json:"queries"`
+}
+
+// goldenQuery is a normalized query stored in a golden file.
+type goldenQuery struct {
+	Operation string      `json:"operation"`
+	Query     string      `json:"query"`
+	Args      []goldenArg `json:"args,omitempty"`
+}
+
+// goldenArg is a bind argument stored with its type, so that values
+// round-trip without losing the distinction between e.g. int64 and float64.
+// Collapsed IN lists are stored as type "list" with their elements in Values.
+// Driver-specific types that are not a driver.Valuer or based on a basic type
+// are stored with their Go type and formatted value, and compare equal to
+// values of that type that format the same.
+type goldenArg struct {
+	Type   string      `json:"type"`
+	Value  string      `json:"value,omitempty"`
+	Values []goldenArg `json:"values,omitempty"`
+}
+
+// writeGolden writes the normalized queries to a golden file.
+func writeGolden(path string, queries []Query) error {
+	file := goldenFile{Queries: make([]goldenQuery, len(queries))}
+	for i, q := range queries {
+		args := make([]goldenArg, len(q.NormalizedArgs))
+		for j, arg := range q.NormalizedArgs {
+			encoded, err := encodeGoldenArg(arg)
+			if err != nil {
+				return fmt.Errorf("query %d arg %d: %w", i, j, err)
+			}
+			args[j] = encoded
+		}
+		file.Queries[i] = goldenQuery{
+			Operation: q.Operation.String(),
+			Query:     q.Normalized,
+			Args:      args,
+		}
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// readGolden reads normalized queries from a golden file.
+// Raw is set to the normalized query since the original text is not stored.
+func readGolden(path string) ([]Query, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file goldenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	queries := make([]Query, len(file.Queries))
+	for i, gq := range file.Queries {
+		args := make([]any, len(gq.Args))
+		for j, ga := range gq.Args {
+			arg, err := decodeGoldenArg(ga)
+			if err != nil {
+				return nil, fmt.Errorf("query %d arg %d: %w", i, j, err)
+			}
+			args[j] = arg
+		}
+		queries[i] = Query{
+			Raw:            gq.Query,
+			Normalized:     gq.Query,
+			Args:           args,
+			NormalizedArgs: args,
+			Operation:      parseOperation(gq.Operation),
+		}
+	}
+	return queries, nil
+}
+
+// encodeGoldenArg encodes a driver value with its type.
+func encodeGoldenArg(arg any) (goldenArg, error) {
+	switch v := arg.(type) {
+	case nil:
+		return goldenArg{Type: "null"}, nil
+	case int64:
+		return goldenArg{Type: "int64", Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return goldenArg{Type: "float64", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case bool:
+		return goldenArg{Type: "bool", Value: strconv.FormatBool(v)}, nil
+	case string:
+		return goldenArg{Type: "string", Value: v}, nil
+	case []byte:
+		return goldenArg{Type: "bytes", Value: string(v)}, nil
+	case time.Time:
+		return goldenArg{Type: "time", Value: v.Format(time.RFC3339Nano)}, nil
+	case []any:
+		values := make([]goldenArg, len(v))
+		for i, elem := range v {
+			encoded, err := encodeGoldenArg(elem)
+			if err != nil {
+				return goldenArg{}, err
+			}
+			values[i] = encoded
+		}
+		return goldenArg{Type: "list", Values: values}, nil
+	}
+
+	// Drivers accept their own argument types in proxy mode
+	if valuer, ok := arg.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return goldenArg{}, fmt.Errorf("argument of type %T: %w", arg, err)
+		}
+		return encodeGoldenArg(value)
+	}
+	rv := reflect.ValueOf(arg)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodeGoldenArg(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return encodeGoldenArg(int64(u))
+		}
+	case reflect.Float32, reflect.Float64:
+		return encodeGoldenArg(rv.Float())
+	case reflect.Bool:
+		return encodeGoldenArg(rv.Bool())
+	case reflect.String:
+		return encodeGoldenArg(rv.String())
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return goldenArg{Type: "null"}, nil
+		}
+		return encodeGoldenArg(rv.Elem().Interface())
+	}
+	return goldenArg{Type: fmt.Sprintf("%T", arg), Value: fmt.Sprint(arg)}, nil
+}
+
+// decodeGoldenArg decodes a driver value encoded by encodeGoldenArg.
+func decodeGoldenArg(arg goldenArg) (any, error) {
+	switch arg.Type {
+	case "null":
+		return nil, nil
+	case "int64":
+		return strconv.ParseInt(arg.Value, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(arg.Value, 64)
+	case "bool":
+		return strconv.ParseBool(arg.Value)
+	case "string":
+		return arg.Value, nil
+	case "bytes":
+		return []byte(arg.Value), nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, arg.Value)
+	case "list":
+		values := make([]any, len(arg.Values))
+		for i, elem := range arg.Values {
+			decoded, err := decodeGoldenArg(elem)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = decoded
+		}
+		return values, nil
+	case "":
+		return nil, fmt.Errorf("argument without type")
+	default:
+		return comparator.FormattedValue{Type: arg.Type, Text: arg.Value}, nil
+	}
+}
+
+// parseOperation parses the string form of an OperationType.
+func parseOperation(s string) OperationType {
+	for op := OperationSelect; op <= OperationRollback; op++ {
+		if op.String() == s {
+			return op
+		}
+	}
+	return OperationOther
+}
@@ -0,0 +1,84 @@
+package migratiorm
+
+import (
+	"regexp"
+	"slices"
+)
+
+// ignoreRule reports whether a query is left out of the comparison.
+type ignoreRule func(q Query) bool
+
+// ignorePattern returns a rule matching normalized queries against pattern.
+// It panics if pattern is not a valid regular expression.
+func ignorePattern(pattern string) ignoreRule {
+	re := regexp.MustCompile(pattern)
+	return func(q Query) bool {
+		return re.MatchString(q.Normalized)
+	}
+}
+
+// ignoreOperations returns a rule matching queries of the given operation types.
+func ignoreOperations(ops []OperationType) ignoreRule {
+	return func(q Query) bool {
+		return slices.Contains(ops, q.Operation)
+	}
+}
+
+// WithIgnoreQuery ignores queries whose normalized text matches the regular
+// expression pattern, e.g. `^SELECT VERSION\(\)$`. Ignored queries are left
+// out of the comparison on both sides, but still listed (dimmed) in the
+// failure output for context.
+// It panics if pattern is not a valid regular expression.
+func WithIgnoreQuery(pattern string) Option {
+	return func(o *options) {
+		o.ignore = append(o.ignore, ignorePattern(pattern))
+	}
+}
+
+// WithIgnoreOperation ignores queries of the given operation types,
+// e.g. OperationOther for SET NAMES or schema introspection.
+func WithIgnoreOperation(ops ...OperationType) Option {
+	return func(o *options) {
+		o.ignore = append(o.ignore, ignoreOperations(ops))
+	}
+}
+
+// WithIgnoreFunc ignores queries for which fn returns true.
+func WithIgnoreFunc(fn func(q Query) bool) Option {
+	return func(o *options) {
+		o.ignore = append(o.ignore, fn)
+	}
+}
+
+// IgnoreQuery is like WithIgnoreQuery for a single assertion.
+func IgnoreQuery(pattern string) AssertOption {
+	return func(o *assertOptions) {
+		o.ignore = append(o.ignore, ignorePattern(pattern))
+	}
+}
+
+// IgnoreOperation is like WithIgnoreOperation for a single assertion.
+func IgnoreOperation(ops ...OperationType) AssertOption {
+	return func(o *assertOptions) {
+		o.ignore = append(o.ignore, ignoreOperations(ops))
+	}
+}
+
+// IgnoreFunc is like WithIgnoreFunc for a single assertion.
+func IgnoreFunc(fn func(q Query) bool) AssertOption {
+	return func(o *assertOptions) {
+		o.ignore = append(o.ignore, fn)
+	}
+}
+
+// ignored reports whether any of the rules matches the query.
+func ignored(q Query, rules ...[]ignoreRule) bool {
+	for _, set := range rules {
+		for _, rule := range set {
+			if rule(q) {
+				return true
+			}
+		}
+	}
+	return false
+}
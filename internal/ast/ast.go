@@ -0,0 +1,71 @@
+// Package ast defines a parsed representation of SQL statements that is
+// compared structurally instead of as normalized strings.
+package ast
+
+// Statement is a parsed SELECT, INSERT, UPDATE or DELETE statement.
+type Statement struct {
+	Type    string    // Statement keyword: SELECT, INSERT, UPDATE or DELETE
+	Clauses []*Clause // Clauses in source order
+}
+
+// ClauseKind determines how the items of a clause are compared and described.
+type ClauseKind int
+
+const (
+	// ClauseExpr is a clause with a single expression (LIMIT ?, FOR UPDATE).
+	ClauseExpr ClauseKind = iota
+	// ClauseList is a comma-separated list of expressions (GROUP BY, ORDER BY).
+	ClauseList
+	// ClauseSelect is a SELECT column list.
+	ClauseSelect
+	// ClauseTables is a list of tables (FROM, UPDATE, INSERT INTO).
+	ClauseTables
+	// ClausePredicates is a list of AND-ed predicates (WHERE, HAVING, JOIN ... ON).
+	ClausePredicates
+	// ClauseAssignments is a list of column = value assignments (SET).
+	ClauseAssignments
+	// ClauseColumns is an INSERT column list.
+	ClauseColumns
+	// ClauseValues is a row of INSERT values, keyed by column.
+	ClauseValues
+	// ClauseReturning is a RETURNING column list.
+	ClauseReturning
+	// ClauseSetOperation is a UNION, INTERSECT or EXCEPT with the combined query.
+	ClauseSetOperation
+)
+
+// noun returns the word describing an item of a clause of this kind.
+func (k ClauseKind) noun() string {
+	switch k {
+	case ClauseSelect, ClauseColumns, ClauseReturning:
+		return "column"
+	case ClauseTables:
+		return "table"
+	case ClausePredicates:
+		return "predicate"
+	case ClauseAssignments:
+		return "assignment"
+	case ClauseValues:
+		return "value"
+	case ClauseSetOperation:
+		return "query"
+	default:
+		return "expression"
+	}
+}
+
+// Clause is a clause of a statement.
+type Clause struct {
+	Name  string // Clause keywords, e.g. WHERE, ORDER BY, LEFT JOIN orders ON
+	Kind  ClauseKind
+	Items []*Expr
+}
+
+// Expr is an item of a clause: a column, table, predicate, assignment or value.
+type Expr struct {
+	SQL        string       // Normalized SQL of the expression
+	Template   string       // SQL with nested statements elided as (SELECT …)
+	Key        string       // Column an assignment or value is bound to, "" otherwise
+	Args       []int        // Indexes of the bind arguments outside nested statements, in order
+	Subqueries []*Statement // Nested statements, in order
+}
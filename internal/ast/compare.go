@@ -0,0 +1,346 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Equivalences relaxes the structural comparison of statements.
+// The zero value compares every clause item in order.
+type Equivalences struct {
+	IgnoreSelectColumns    bool // SELECT column lists are not compared (SELECT * ≡ SELECT id, name)
+	UnorderedInsertColumns bool // INSERT columns and values are compared by column, ignoring order
+	UnorderedAssignments   bool // SET assignments are compared by column, ignoring order
+	UnorderedPredicates    bool // AND-ed predicates are compared as sets
+	IgnoreReturning        bool // RETURNING clauses are not compared
+}
+
+// Result is the result of comparing two statements.
+type Result struct {
+	// Differences describes each difference as a path into the statement,
+	// e.g. "WHERE: predicate `deleted_at IS NULL` missing".
+	// It is empty if the statements are equivalent.
+	Differences []string
+	// ArgMap maps the index of each expected bind argument to the index of the
+	// actual argument in the same position of the tree.
+	ArgMap map[int]int
+}
+
+// Equal reports whether the statements are equivalent.
+func (r Result) Equal() bool {
+	return len(r.Differences) == 0
+}
+
+// Compare compares two statements structurally.
+func Compare(expected, actual *Statement, eq Equivalences) Result {
+	c := newComparison(eq)
+	c.statements(expected, actual)
+	return Result{Differences: c.diffs, ArgMap: c.argMap}
+}
+
+// comparison accumulates the differences and argument mapping of a comparison.
+type comparison struct {
+	eq       Equivalences
+	diffs    []string
+	argMap   map[int]int
+	mismatch bool // Set by exprs when the expressions differ outside nested statements
+}
+
+func newComparison(eq Equivalences) *comparison {
+	return &comparison{eq: eq, argMap: make(map[int]int)}
+}
+
+// equal reports whether the compared nodes were equivalent.
+func (c *comparison) equal() bool {
+	return !c.mismatch && len(c.diffs) == 0
+}
+
+// merge adds the differences and argument mapping of a nested comparison,
+// prefixing its differences with prefix.
+func (c *comparison) merge(sub *comparison, prefix string) {
+	for _, d := range sub.diffs {
+		c.diffs = append(c.diffs, prefix+d)
+	}
+	for e, a := range sub.argMap {
+		c.argMap[e] = a
+	}
+}
+
+func (c *comparison) addf(format string, args ...any) {
+	c.diffs = append(c.diffs, fmt.Sprintf(format, args...))
+}
+
+// statements compares two statements, pairing their clauses by name.
+func (c *comparison) statements(expected, actual *Statement) {
+	if expected.Type != actual.Type {
+		c.addf("statement: %s changed to %s", expected.Type, actual.Type)
+		return
+	}
+
+	used := make([]bool, len(actual.Clauses))
+	for _, e := range expected.Clauses {
+		var paired *Clause
+		for j, a := range actual.Clauses {
+			if !used[j] && a.Name == e.Name {
+				paired = a
+				used[j] = true
+				break
+			}
+		}
+		c.clauses(e, paired)
+	}
+	for j, a := range actual.Clauses {
+		if !used[j] {
+			c.clauses(nil, a)
+		}
+	}
+}
+
+// clauses compares two clauses with the same name. Either may be nil when the
+// clause only exists on one side, in which case its items are reported.
+func (c *comparison) clauses(expected, actual *Clause) {
+	clause := expected
+	if clause == nil {
+		clause = actual
+	}
+
+	switch {
+	case c.eq.IgnoreReturning && clause.Kind == ClauseReturning:
+		return
+	case expected == nil && len(actual.Items) == 0:
+		c.addf("%s: clause unexpected", actual.Name)
+		return
+	case actual == nil && len(expected.Items) == 0:
+		c.addf("%s: clause missing", expected.Name)
+		return
+	}
+
+	var expectedItems, actualItems []*Expr
+	if expected != nil {
+		expectedItems = expected.Items
+	}
+	if actual != nil {
+		actualItems = actual.Items
+	}
+
+	if c.eq.IgnoreSelectColumns && clause.Kind == ClauseSelect {
+		c.ignoredItems(expectedItems, actualItems)
+		return
+	}
+
+	keyed := clause.Kind == ClauseAssignments || clause.Kind == ClauseValues
+	switch {
+	case keyed && c.unordered(clause.Kind):
+		c.keyedItems(clause, expectedItems, actualItems)
+	case c.unordered(clause.Kind):
+		c.unorderedItems(clause, expectedItems, actualItems)
+	default:
+		c.orderedItems(clause, expectedItems, actualItems)
+	}
+}
+
+// unordered reports whether items of a clause kind are compared ignoring order.
+func (c *comparison) unordered(kind ClauseKind) bool {
+	switch kind {
+	case ClausePredicates:
+		return c.eq.UnorderedPredicates
+	case ClauseAssignments:
+		return c.eq.UnorderedAssignments
+	case ClauseColumns, ClauseValues:
+		return c.eq.UnorderedInsertColumns
+	}
+	return false
+}
+
+// ignoredItems maps the arguments of items that are not compared by position,
+// provided both sides bind the same number of arguments.
+func (c *comparison) ignoredItems(expected, actual []*Expr) {
+	var expectedArgs, actualArgs []int
+	for _, e := range expected {
+		expectedArgs = append(expectedArgs, allArgs(e)...)
+	}
+	for _, a := range actual {
+		actualArgs = append(actualArgs, allArgs(a)...)
+	}
+	if len(expectedArgs) == len(actualArgs) {
+		for i, e := range expectedArgs {
+			c.argMap[e] = actualArgs[i]
+		}
+	}
+}
+
+// keyedItems compares assignments or values by the column they are bound to.
+func (c *comparison) keyedItems(clause *Clause, expected, actual []*Expr) {
+	used := make([]bool, len(actual))
+	for _, e := range expected {
+		paired := -1
+		for j, a := range actual {
+			if !used[j] && a.Key == e.Key {
+				paired = j
+				break
+			}
+		}
+		if paired < 0 {
+			c.addf("%s: %s `%s` missing", clause.Name, clause.Kind.noun(), e.SQL)
+			continue
+		}
+		used[paired] = true
+		c.pair(clause, e, actual[paired])
+	}
+	c.unexpected(clause, actual, used)
+}
+
+// unorderedItems compares items as multisets.
+func (c *comparison) unorderedItems(clause *Clause, expected, actual []*Expr) {
+	pairedWith, used := c.matchEqual(expected, actual)
+	c.pairRemaining(clause, expected, actual, pairedWith, used)
+}
+
+// orderedItems compares items in order. Equal items are paired first, so that
+// a single added or removed item is reported as such; if every item has an
+// equal counterpart but the order differs, the reordering is reported.
+func (c *comparison) orderedItems(clause *Clause, expected, actual []*Expr) {
+	pairedWith, used := c.matchEqual(expected, actual)
+
+	complete := len(expected) == len(actual)
+	inOrder := true
+	for i, j := range pairedWith {
+		if j < 0 {
+			complete = false
+		} else if j != i {
+			inOrder = false
+		}
+	}
+	if complete && !inOrder {
+		c.addf("%s: order of %ss changed from `%s` to `%s`",
+			clause.Name, clause.Kind.noun(), joinSQL(expected), joinSQL(actual))
+		return
+	}
+
+	c.pairRemaining(clause, expected, actual, pairedWith, used)
+}
+
+// matchEqual pairs each expected item with the first unused equal actual item
+// and merges the argument mapping of the pairs.
+func (c *comparison) matchEqual(expected, actual []*Expr) ([]int, []bool) {
+	pairedWith := make([]int, len(expected))
+	used := make([]bool, len(actual))
+	for i, e := range expected {
+		pairedWith[i] = -1
+		for j, a := range actual {
+			if used[j] {
+				continue
+			}
+			if sub := c.exprs(e, a); sub.equal() {
+				c.merge(sub, "")
+				pairedWith[i] = j
+				used[j] = true
+				break
+			}
+		}
+	}
+	return pairedWith, used
+}
+
+// pairRemaining reports the items left unpaired by matchEqual. Items with the
+// same shape are compared in depth, then the remaining items are paired by
+// position as changes, and any left over are missing or unexpected.
+func (c *comparison) pairRemaining(clause *Clause, expected, actual []*Expr, pairedWith []int, used []bool) {
+	var rest []*Expr
+	for i, e := range expected {
+		if pairedWith[i] >= 0 {
+			continue
+		}
+		paired := false
+		for j, a := range actual {
+			if !used[j] && a.Template == e.Template {
+				used[j] = true
+				c.pair(clause, e, a)
+				paired = true
+				break
+			}
+		}
+		if !paired {
+			rest = append(rest, e)
+		}
+	}
+
+	for _, e := range rest {
+		paired := false
+		for j, a := range actual {
+			if !used[j] {
+				used[j] = true
+				c.pair(clause, e, a)
+				paired = true
+				break
+			}
+		}
+		if !paired {
+			c.addf("%s: %s `%s` missing", clause.Name, clause.Kind.noun(), e.SQL)
+		}
+	}
+	c.unexpected(clause, actual, used)
+}
+
+// unexpected reports the actual items that were not paired.
+func (c *comparison) unexpected(clause *Clause, actual []*Expr, used []bool) {
+	for j, a := range actual {
+		if !used[j] {
+			c.addf("%s: %s `%s` unexpected", clause.Name, clause.Kind.noun(), a.SQL)
+		}
+	}
+}
+
+// pair compares two items paired with each other and reports their differences.
+func (c *comparison) pair(clause *Clause, expected, actual *Expr) {
+	sub := c.exprs(expected, actual)
+	switch {
+	case sub.mismatch:
+		c.addf("%s: %s `%s` changed to `%s`", clause.Name, clause.Kind.noun(), expected.SQL, actual.SQL)
+	case clause.Kind == ClauseSetOperation:
+		c.merge(sub, clause.Name+": ")
+	default:
+		c.merge(sub, fmt.Sprintf("%s: %s `%s`: subquery ", clause.Name, clause.Kind.noun(), expected.Template))
+	}
+}
+
+// exprs compares two expressions and their nested statements.
+func (c *comparison) exprs(expected, actual *Expr) *comparison {
+	sub := newComparison(c.eq)
+	if expected.Template != actual.Template || len(expected.Subqueries) != len(actual.Subqueries) {
+		sub.mismatch = true
+		return sub
+	}
+
+	for i, e := range expected.Args {
+		if i < len(actual.Args) {
+			sub.argMap[e] = actual.Args[i]
+		}
+	}
+	for i, e := range expected.Subqueries {
+		sub.statements(e, actual.Subqueries[i])
+	}
+	return sub
+}
+
+// allArgs returns the arguments of an expression including nested statements.
+func allArgs(e *Expr) []int {
+	args := append([]int{}, e.Args...)
+	for _, s := range e.Subqueries {
+		for _, clause := range s.Clauses {
+			for _, item := range clause.Items {
+				args = append(args, allArgs(item)...)
+			}
+		}
+	}
+	return args
+}
+
+// joinSQL joins the SQL of items with commas.
+func joinSQL(items []*Expr) string {
+	sqls := make([]string, len(items))
+	for i, item := range items {
+		sqls[i] = item.SQL
+	}
+	return strings.Join(sqls, ", ")
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -18,24 +19,67 @@ type RawQuery struct {
 	Args  []any
 }
 
+// Response is a canned result returned for queries accepted by Match.
+type Response struct {
+	Match        func(query string, args []any) bool // Reports whether the response applies
+	Columns      []string                            // Column names of the result set
+	Rows         [][]any                             // Row values, converted to driver values
+	RowsAffected int64                               // Value returned by Result.RowsAffected
+	LastInsertID int64                               // Value returned by Result.LastInsertId
+}
+
+// Options contains configuration for the capturer.
+type Options struct {
+	Responses          []Response // Canned responses, the first match wins
+	RecordTransactions bool       // Record BEGIN/COMMIT/ROLLBACK as queries
+
+	// Proxy mode: queries are recorded and forwarded to a real database.
+	// Connector takes precedence over Driver and DSN.
+	Connector driver.Connector // Connector of the real database
+	Driver    driver.Driver    // Driver of the real database, opened with DSN
+	DSN       string           // Data source name passed to Driver
+}
+
 // Capturer captures SQL queries executed against a database.
 type Capturer struct {
 	db         *sql.DB
+	direct     *sql.DB // Non-recording connection of the real database in proxy mode
 	driver     *capturingDriver
 	normalizer *normalizer.Normalizer
 }
 
 // New creates a new Capturer instance.
-func New(n *normalizer.Normalizer) (*Capturer, error) {
+func New(n *normalizer.Normalizer, opts Options) (*Capturer, error) {
+	responses, err := convertResponses(opts.Responses)
+	if err != nil {
+		return nil, err
+	}
+
 	drv := &capturingDriver{
-		queries:    make([]RawQuery, 0),
-		normalizer: n,
+		queries:            make([]RawQuery, 0),
+		responses:          responses,
+		recordTransactions: opts.RecordTransactions,
+		normalizer:         n,
 	}
 
-	// Register the driver with a unique name
-	driverName := drv.register()
+	target, err := opts.target()
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := sql.Open(driverName, "")
+	// In proxy mode, every connection is a fresh connection of the real
+	// database that records statements before forwarding them
+	if target != nil {
+		return &Capturer{
+			db:         sql.OpenDB(&proxyConnector{target: target, driver: drv}),
+			direct:     sql.OpenDB(directConnector{target}),
+			driver:     drv,
+			normalizer: n,
+		}, nil
+	}
+
+	// Register the driver with a unique name
+	db, err := sql.Open(drv.register(), "")
 	if err != nil {
 		return nil, err
 	}
@@ -47,18 +91,35 @@ func New(n *normalizer.Normalizer) (*Capturer, error) {
 	}, nil
 }
 
+// target returns the connector of the real database, or nil without proxy mode.
+func (o Options) target() (driver.Connector, error) {
+	if o.Connector != nil || o.Driver == nil {
+		return o.Connector, nil
+	}
+	return driverConnector(o.Driver, o.DSN)
+}
+
 // DB returns the database connection for use by ORMs.
 func (c *Capturer) DB() *sql.DB {
 	return c.db
 }
 
+// Direct returns a connection of the real database that does not record
+// queries, or nil without proxy mode.
+func (c *Capturer) Direct() *sql.DB {
+	return c.direct
+}
+
 // RawQueries returns all captured raw queries.
 func (c *Capturer) RawQueries() []RawQuery {
 	return c.driver.RawQueries()
 }
 
-// Close closes the database connection.
+// Close closes the database connections.
 func (c *Capturer) Close() error {
+	if c.direct != nil {
+		c.direct.Close() //nolint:errcheck
+	}
 	return c.db.Close()
 }
 
@@ -69,9 +130,11 @@ func (c *Capturer) Normalizer() *normalizer.Normalizer {
 
 // capturingDriver is a database driver that captures all executed queries.
 type capturingDriver struct {
-	queries    []RawQuery
-	normalizer *normalizer.Normalizer
-	mu         sync.Mutex
+	queries            []RawQuery
+	responses          []response
+	recordTransactions bool
+	normalizer         *normalizer.Normalizer
+	mu                 sync.Mutex
 }
 
 var driverCounter int64
@@ -95,6 +158,39 @@ func (d *capturingDriver) recordQuery(query string, args []any) {
 	d.queries = append(d.queries, RawQuery{Query: query, Args: args})
 }
 
+// recordTransaction records a transaction boundary if enabled.
+func (d *capturingDriver) recordTransaction(statement string) {
+	if d.recordTransactions {
+		d.recordQuery(statement, nil)
+	}
+}
+
+// respond returns the first response matching the query, or nil.
+func (d *capturingDriver) respond(query string, args []any) *response {
+	for i := range d.responses {
+		if d.responses[i].match(query, args) {
+			return &d.responses[i]
+		}
+	}
+	return nil
+}
+
+// rows returns the rows for a query, falling back to an empty result set.
+func (d *capturingDriver) rows(query string, args []any) driver.Rows {
+	if r := d.respond(query, args); r != nil {
+		return &cannedRows{columns: r.columns, rows: r.rows}
+	}
+	return &emptyRows{}
+}
+
+// result returns the result for a statement, falling back to zero values.
+func (d *capturingDriver) result(query string, args []any) driver.Result {
+	if r := d.respond(query, args); r != nil {
+		return &cannedResult{rowsAffected: r.rowsAffected, lastInsertID: r.lastInsertID}
+	}
+	return &emptyResult{}
+}
+
 // RawQueries returns all captured raw queries.
 func (d *capturingDriver) RawQueries() []RawQuery {
 	d.mu.Lock()
@@ -119,19 +215,27 @@ func (c *capturingConn) Close() error {
 }
 
 func (c *capturingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// Implement driver.ConnBeginTx to capture transaction options.
+func (c *capturingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.driver.recordTransaction(beginStatement(opts))
 	return &capturingTx{conn: c}, nil
 }
 
 // Implement driver.QueryerContext for direct Query calls.
 func (c *capturingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	c.driver.recordQuery(query, namedValuesToAny(args))
-	return &emptyRows{}, nil
+	values := namedValuesToAny(args)
+	c.driver.recordQuery(query, values)
+	return c.driver.rows(query, values), nil
 }
 
 // Implement driver.ExecerContext for direct Exec calls.
 func (c *capturingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	c.driver.recordQuery(query, namedValuesToAny(args))
-	return &emptyResult{}, nil
+	values := namedValuesToAny(args)
+	c.driver.recordQuery(query, values)
+	return c.driver.result(query, values), nil
 }
 
 // capturingStmt is a prepared statement that captures queries.
@@ -149,28 +253,45 @@ func (s *capturingStmt) NumInput() int {
 }
 
 func (s *capturingStmt) Exec(args []driver.Value) (driver.Result, error) {
-	s.conn.driver.recordQuery(s.query, valuesToAny(args))
-	return &emptyResult{}, nil
+	values := valuesToAny(args)
+	s.conn.driver.recordQuery(s.query, values)
+	return s.conn.driver.result(s.query, values), nil
 }
 
 func (s *capturingStmt) Query(args []driver.Value) (driver.Rows, error) {
-	s.conn.driver.recordQuery(s.query, valuesToAny(args))
-	return &emptyRows{}, nil
+	values := valuesToAny(args)
+	s.conn.driver.recordQuery(s.query, values)
+	return s.conn.driver.rows(s.query, values), nil
 }
 
-// capturingTx is a transaction that does nothing but satisfies the interface.
+// capturingTx is a transaction that records its outcome.
 type capturingTx struct {
 	conn *capturingConn
 }
 
 func (t *capturingTx) Commit() error {
+	t.conn.driver.recordTransaction("COMMIT")
 	return nil
 }
 
 func (t *capturingTx) Rollback() error {
+	t.conn.driver.recordTransaction("ROLLBACK")
 	return nil
 }
 
+// beginStatement renders a BEGIN statement including the transaction options.
+// BEGIN ISOLATION LEVEL SERIALIZABLE READ ONLY
+func beginStatement(opts driver.TxOptions) string {
+	parts := []string{"BEGIN"}
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		parts = append(parts, "ISOLATION LEVEL", strings.ToUpper(level.String()))
+	}
+	if opts.ReadOnly {
+		parts = append(parts, "READ ONLY")
+	}
+	return strings.Join(parts, " ")
+}
+
 // emptyResult is a result that returns zero values.
 type emptyResult struct{}
 
@@ -0,0 +1,286 @@
+package capturer
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// driverConnector returns a connector that opens connections of d with the data source name dsn.
+func driverConnector(d driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return &dsnConnector{driver: d, dsn: dsn}, nil
+}
+
+// dsnConnector is a connector for drivers that do not implement driver.DriverContext.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// proxyConnector opens connections of a real database that record every
+// statement before forwarding it. It does not implement io.Closer, so closing
+// a capture database leaves the user's connector open for the next capture.
+type proxyConnector struct {
+	target driver.Connector
+	driver *capturingDriver
+}
+
+func (c *proxyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.target.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{conn: conn, driver: c.driver}, nil
+}
+
+func (c *proxyConnector) Driver() driver.Driver {
+	return c.target.Driver()
+}
+
+// directConnector hides whether the user's connector implements io.Closer,
+// so that closing a direct database leaves the connector open.
+type directConnector struct {
+	driver.Connector
+}
+
+// proxyConn is a connection that records statements and forwards them to a real connection.
+// Canned responses take precedence over forwarding.
+type proxyConn struct {
+	conn   driver.Conn
+	driver *capturingDriver
+}
+
+func (c *proxyConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// Implement driver.ConnPrepareContext to forward the context.
+func (c *proxyConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if cp, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cp.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &proxyStmt{stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *proxyConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *proxyConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// Implement driver.ConnBeginTx to capture and forward transaction options.
+func (c *proxyConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if cb, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin() //nolint:staticcheck // Fallback for drivers without ConnBeginTx
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.driver.recordTransaction(beginStatement(opts))
+	return &proxyTx{tx: tx, driver: c.driver}, nil
+}
+
+// Implement driver.QueryerContext for direct Query calls.
+// Connections without direct queries return driver.ErrSkip, so that the
+// query is prepared instead and recorded by the statement.
+func (c *proxyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := namedValuesToAny(args)
+	if r := c.driver.respond(query, values); r != nil {
+		c.driver.recordQuery(query, values)
+		return &cannedRows{columns: r.columns, rows: r.rows}, nil
+	}
+
+	q, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := q.QueryContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	c.driver.recordQuery(query, values)
+	return rows, err
+}
+
+// Implement driver.ExecerContext for direct Exec calls.
+// Connections without direct execution return driver.ErrSkip, so that the
+// statement is prepared instead and recorded by the statement.
+func (c *proxyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := namedValuesToAny(args)
+	if r := c.driver.respond(query, values); r != nil {
+		c.driver.recordQuery(query, values)
+		return &cannedResult{rowsAffected: r.rowsAffected, lastInsertID: r.lastInsertID}, nil
+	}
+
+	e, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	result, err := e.ExecContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	c.driver.recordQuery(query, values)
+	return result, err
+}
+
+// Implement driver.NamedValueChecker so that the real driver converts its own argument types.
+func (c *proxyConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// Implement driver.Pinger.
+func (c *proxyConn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// Implement driver.SessionResetter.
+func (c *proxyConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// Implement driver.Validator.
+func (c *proxyConn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// proxyStmt is a prepared statement that records executions and forwards them.
+type proxyStmt struct {
+	stmt  driver.Stmt
+	conn  *proxyConn
+	query string
+}
+
+func (s *proxyStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *proxyStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *proxyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *proxyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+// Implement driver.StmtExecContext to forward the context.
+func (s *proxyStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	values := namedValuesToAny(args)
+	s.conn.driver.recordQuery(s.query, values)
+	if r := s.conn.driver.respond(s.query, values); r != nil {
+		return &cannedResult{rowsAffected: r.rowsAffected, lastInsertID: r.lastInsertID}, nil
+	}
+
+	if e, ok := s.stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	plain, err := namedToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.stmt.Exec(plain) //nolint:staticcheck // Fallback for drivers without StmtExecContext
+}
+
+// Implement driver.StmtQueryContext to forward the context.
+func (s *proxyStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values := namedValuesToAny(args)
+	s.conn.driver.recordQuery(s.query, values)
+	if r := s.conn.driver.respond(s.query, values); r != nil {
+		return &cannedRows{columns: r.columns, rows: r.rows}, nil
+	}
+
+	if q, ok := s.stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	plain, err := namedToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.stmt.Query(plain) //nolint:staticcheck // Fallback for drivers without StmtQueryContext
+}
+
+// Implement driver.NamedValueChecker so that the real driver converts its own argument types.
+func (s *proxyStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// proxyTx is a transaction that records its outcome and forwards it.
+type proxyTx struct {
+	tx     driver.Tx
+	driver *capturingDriver
+}
+
+func (t *proxyTx) Commit() error {
+	t.driver.recordTransaction("COMMIT")
+	return t.tx.Commit()
+}
+
+func (t *proxyTx) Rollback() error {
+	t.driver.recordTransaction("ROLLBACK")
+	return t.tx.Rollback()
+}
+
+// valuesToNamed converts positional driver values to named values.
+func valuesToNamed(values []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, len(values))
+	for i, v := range values {
+		result[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return result
+}
+
+// namedToValues converts named values to positional driver values for drivers
+// that do not support named parameters.
+func namedToValues(values []driver.NamedValue) ([]driver.Value, error) {
+	result := make([]driver.Value, len(values))
+	for i, v := range values {
+		if v.Name != "" {
+			return nil, errors.New("capturer: driver does not support the use of Named Parameters")
+		}
+		result[i] = v.Value
+	}
+	return result, nil
+}
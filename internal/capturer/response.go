@@ -0,0 +1,91 @@
+package capturer
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+// response is a Response whose row values have been converted to driver values.
+type response struct {
+	match        func(query string, args []any) bool
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+	lastInsertID int64
+}
+
+// convertResponses validates the responses and converts their rows to driver values.
+func convertResponses(responses []Response) ([]response, error) {
+	result := make([]response, len(responses))
+	for i, r := range responses {
+		if r.Match == nil {
+			return nil, fmt.Errorf("capturer: response %d has no matcher", i)
+		}
+
+		rows := make([][]driver.Value, len(r.Rows))
+		for j, row := range r.Rows {
+			if len(row) != len(r.Columns) {
+				return nil, fmt.Errorf("capturer: response %d row %d has %d values, want %d", i, j, len(row), len(r.Columns))
+			}
+
+			values := make([]driver.Value, len(row))
+			for k, v := range row {
+				value, err := driver.DefaultParameterConverter.ConvertValue(v)
+				if err != nil {
+					return nil, fmt.Errorf("capturer: response %d row %d column %q: %w", i, j, r.Columns[k], err)
+				}
+				values[k] = value
+			}
+			rows[j] = values
+		}
+
+		result[i] = response{
+			match:        r.Match,
+			columns:      r.Columns,
+			rows:         rows,
+			rowsAffected: r.RowsAffected,
+			lastInsertID: r.LastInsertID,
+		}
+	}
+	return result, nil
+}
+
+// cannedResult is a result that returns the values of a Response.
+type cannedResult struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+func (r *cannedResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r *cannedResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// cannedRows is a rows iterator that returns the rows of a Response.
+type cannedRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *cannedRows) Columns() []string {
+	return r.columns
+}
+
+func (r *cannedRows) Close() error {
+	r.pos = len(r.rows)
+	return nil
+}
+
+func (r *cannedRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -0,0 +1,192 @@
+package comparator
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// argsEqual reports whether two bind argument lists are equal after coercion.
+// Arguments with a matcher are compared by the matcher instead. Arguments that
+// are lists, such as collapsed IN lists, are compared element by element, in
+// any order if unorderedLists is set.
+func argsEqual(expected, actual []any, matchers []ArgMatcher, unorderedLists bool) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if m := matcherAt(matchers, i); m != nil {
+			if !m.Match(expected[i], actual[i]) {
+				return false
+			}
+			continue
+		}
+		if e, ok := expected[i].([]any); ok {
+			if a, ok := actual[i].([]any); ok {
+				if !listsEqual(e, a, unorderedLists) {
+					return false
+				}
+				continue
+			}
+		}
+		if !valuesEqual(expected[i], actual[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// listsEqual reports whether two list arguments hold equal values, in the same
+// order unless unordered is set.
+func listsEqual(expected, actual []any, unordered bool) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	if !unordered {
+		return argsEqual(expected, actual, nil, false)
+	}
+
+	used := make([]bool, len(actual))
+	for _, e := range expected {
+		found := false
+		for j, a := range actual {
+			if !used[j] && valuesEqual(e, a) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matcherAt returns the matcher for the argument at index i, or nil.
+func matcherAt(matchers []ArgMatcher, i int) ArgMatcher {
+	if i < len(matchers) {
+		return matchers[i]
+	}
+	return nil
+}
+
+// FormattedValue is a bind argument known only by its Go type and fmt
+// formatting, such as a driver-specific value read back from a golden file.
+// It equals values of the same type that format the same.
+type FormattedValue struct {
+	Type string // Go type as formatted by %T
+	Text string // Value as formatted by %v
+}
+
+// matches reports whether v has the type and formatting of f.
+func (f FormattedValue) matches(v any) bool {
+	if g, ok := v.(FormattedValue); ok {
+		return f == g
+	}
+	return fmt.Sprintf("%T", v) == f.Type && fmt.Sprint(v) == f.Text
+}
+
+// valuesEqual reports whether two bind arguments are equal after coercion.
+func valuesEqual(expected, actual any) bool {
+	e := coerceValue(expected)
+	a := coerceValue(actual)
+
+	if f, ok := e.(FormattedValue); ok {
+		return f.matches(a)
+	}
+	if f, ok := a.(FormattedValue); ok {
+		return f.matches(e)
+	}
+
+	if et, ok := e.(time.Time); ok {
+		at, ok := a.(time.Time)
+		return ok && et.Equal(at)
+	}
+
+	return reflect.DeepEqual(e, a)
+}
+
+// coerceValue converts equivalent driver values to a canonical type,
+// so that ORM type quirks (int vs int64, []byte vs string) compare equal.
+func coerceValue(v any) any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x
+	case driver.Valuer:
+		if value, err := x.Value(); err == nil {
+			return coerceValue(value)
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return coerceValue(rv.Elem().Interface())
+	default:
+		return v
+	}
+}
+
+// FormatArgs formats bind arguments as a human-readable list.
+func FormatArgs(args []any) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = formatArg(arg)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// formatExpectedArgs formats expected bind arguments, describing matched ones by their matcher.
+func formatExpectedArgs(args []any, matchers []ArgMatcher) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = formatArg(arg)
+		if m := matcherAt(matchers, i); m != nil {
+			parts[i] += " " + m.String()
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// formatArg formats a single bind argument.
+func formatArg(arg any) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%q", v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []any:
+		return FormatArgs(v)
+	case FormattedValue:
+		return v.Text
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
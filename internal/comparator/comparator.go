@@ -3,6 +3,8 @@ package comparator
 import (
 	"fmt"
 	"strings"
+
+	"github.com/ucpr/migratiorm/internal/ast"
 )
 
 // CompareMode defines how queries should be compared.
@@ -10,9 +12,18 @@ type CompareMode int
 
 const (
 	// CompareStrict requires queries to match in exact order.
+	// Differences are reported against an alignment of the two sequences,
+	// so an inserted or removed query does not shift the following ones.
 	CompareStrict CompareMode = iota
 	// CompareUnordered compares queries as sets, ignoring order.
 	CompareUnordered
+	// CompareSubset requires the expected queries to appear in order among the
+	// actual queries. Additional actual queries are reported as DiffAdded, and
+	// expected queries that are not found as DiffMissing.
+	CompareSubset
+	// CompareSubsetUnordered requires every expected query to appear among the
+	// actual queries, ignoring order. Additional actual queries are reported as DiffAdded.
+	CompareSubsetUnordered
 )
 
 // DiffType represents the type of difference found.
@@ -27,6 +38,14 @@ const (
 	DiffExtra
 	// DiffModified indicates queries at same position differ.
 	DiffModified
+	// DiffArgs indicates queries match but their bind arguments differ.
+	DiffArgs
+	// DiffIgnored indicates a query excluded from the comparison, listed for context.
+	DiffIgnored
+	// DiffAdded indicates an actual query not in expected that the subset modes allow.
+	DiffAdded
+	// DiffAccepted indicates a difference that was reviewed and accepted.
+	DiffAccepted
 )
 
 func (d DiffType) String() string {
@@ -39,17 +58,44 @@ func (d DiffType) String() string {
 		return "EXTRA"
 	case DiffModified:
 		return "MODIFIED"
+	case DiffArgs:
+		return "ARGS"
+	case DiffIgnored:
+		return "IGNORED"
+	case DiffAdded:
+		return "ADDED"
+	case DiffAccepted:
+		return "ACCEPTED"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// Query is a normalized query together with its bind arguments.
+type Query struct {
+	SQL      string
+	Args     []any
+	Matchers []ArgMatcher // Optional matchers aligned with Args; nil entries compare exactly
+
+	Statement *ast.Statement // Parsed statement for structural comparison, nil if not parsed
+	Ignored   bool           // Excluded from the comparison and reported as DiffIgnored
+}
+
 // Difference represents a single difference between expected and actual queries.
 type Difference struct {
-	Type     DiffType
-	Index    int
-	Expected string
-	Actual   string
+	Type         DiffType
+	Index        int
+	Expected     string
+	Actual       string
+	ExpectedArgs []any
+	ActualArgs   []any
+	Matchers     []ArgMatcher
+	Details      []string // Structural differences of a DiffModified in structural mode
+	Reason       string   // Reason a DiffAccepted was accepted
+	Trace        []string // Normalization trace lines of a DiffModified, printed when set
+
+	ExpectedIndex int // Position of the expected query, -1 for DiffExtra
+	ActualIndex   int // Position of the actual query, -1 for DiffMissing
 }
 
 // CompareResult holds the result of comparing two query sets.
@@ -58,129 +104,435 @@ type CompareResult struct {
 	Differences []Difference
 }
 
+// Options contains configuration for the comparator.
+type Options struct {
+	Mode        CompareMode // How queries are matched (default: CompareStrict)
+	CompareArgs bool        // Compare bind arguments of matching queries (default: true)
+
+	// UnorderedLists compares list arguments, such as collapsed IN lists,
+	// regardless of the order of their elements (default: false).
+	UnorderedLists bool
+
+	// Structural compares parsed statements instead of normalized SQL when both
+	// queries could be parsed (default: false).
+	Structural   bool
+	Equivalences ast.Equivalences // Equivalences applied in structural mode
+
+	Accepted []Accepted // Reviewed differences that do not fail the comparison
+}
+
+// Accepted is a reviewed MODIFIED, MISSING or EXTRA difference, keyed by its
+// expected and actual normalized queries. A difference that changes in any
+// way no longer matches and fails again.
+type Accepted struct {
+	Expected string // Expected query, "" for an EXTRA query
+	Actual   string // Actual query, "" for a MISSING query
+	Reason   string // Why the difference is acceptable
+}
+
+// DefaultOptions returns the default comparator options.
+func DefaultOptions() Options {
+	return Options{
+		Mode:        CompareStrict,
+		CompareArgs: true,
+	}
+}
+
 // Comparator compares query sets and reports differences.
 type Comparator struct {
-	mode CompareMode
+	options Options
 }
 
-// New creates a new Comparator with the given mode.
-func New(mode CompareMode) *Comparator {
-	return &Comparator{mode: mode}
+// New creates a new Comparator with the given options.
+func New(opts Options) *Comparator {
+	return &Comparator{options: opts}
 }
 
-// Compare compares expected and actual normalized query strings.
-func (c *Comparator) Compare(expected, actual []string) CompareResult {
-	switch c.mode {
+// Compare compares expected and actual normalized queries.
+// Ignored queries are left out of the comparison and listed among the
+// differences next to the queries around them.
+func (c *Comparator) Compare(expected, actual []Query) CompareResult {
+	comparedExpected, expectedIndexes := compared(expected)
+	comparedActual, actualIndexes := compared(actual)
+
+	var result CompareResult
+	switch c.options.Mode {
 	case CompareUnordered:
-		return c.compareUnordered(expected, actual)
+		result = c.compareUnordered(comparedExpected, comparedActual)
+	case CompareSubset:
+		result = allowExtra(c.compareOrdered(comparedExpected, comparedActual, false))
+	case CompareSubsetUnordered:
+		result = allowExtra(c.compareUnordered(comparedExpected, comparedActual))
 	default:
-		return c.compareStrict(expected, actual)
+		result = c.compareOrdered(comparedExpected, comparedActual, true)
+	}
+
+	result = c.accept(result)
+	result.Equal = equal(result.Differences)
+
+	return withIgnored(result, expected, actual, expectedIndexes, actualIndexes)
+}
+
+// accept marks differences matching an accepted difference as DiffAccepted.
+func (c *Comparator) accept(result CompareResult) CompareResult {
+	for i, diff := range result.Differences {
+		if diff.Type != DiffModified && diff.Type != DiffMissing && diff.Type != DiffExtra {
+			continue
+		}
+		for _, a := range c.options.Accepted {
+			if a.Expected == diff.Expected && a.Actual == diff.Actual {
+				result.Differences[i].Type = DiffAccepted
+				result.Differences[i].Reason = a.Reason
+				break
+			}
+		}
 	}
+	return result
+}
+
+// equal reports whether none of the differences fails the comparison.
+func equal(differences []Difference) bool {
+	for _, diff := range differences {
+		switch diff.Type {
+		case DiffMatch, DiffIgnored, DiffAdded, DiffAccepted:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// allowExtra reports extra actual queries as DiffAdded, which does not fail the comparison.
+func allowExtra(result CompareResult) CompareResult {
+	for i := range result.Differences {
+		if result.Differences[i].Type == DiffExtra {
+			result.Differences[i].Type = DiffAdded
+		}
+	}
+	return result
 }
 
-// compareStrict compares queries in order.
-func (c *Comparator) compareStrict(expected, actual []string) CompareResult {
+// compared returns the queries that are not ignored and their original positions.
+func compared(queries []Query) ([]Query, []int) {
+	result := make([]Query, 0, len(queries))
+	indexes := make([]int, 0, len(queries))
+	for i, q := range queries {
+		if !q.Ignored {
+			result = append(result, q)
+			indexes = append(indexes, i)
+		}
+	}
+	return result, indexes
+}
+
+// withIgnored maps the query positions of a result back to the original
+// queries and inserts an entry for each ignored query before the first
+// difference of a later query on the same side.
+func withIgnored(result CompareResult, expected, actual []Query, expectedIndexes, actualIndexes []int) CompareResult {
+	differences := make([]Difference, 0, len(result.Differences))
+	nextExpected, nextActual := 0, 0
+
+	// flush adds the ignored queries before the given original positions
+	flush := func(expectedEnd, actualEnd int) {
+		for ; nextExpected < expectedEnd; nextExpected++ {
+			if q := expected[nextExpected]; q.Ignored {
+				differences = append(differences, Difference{
+					Type:          DiffIgnored,
+					Expected:      q.SQL,
+					ExpectedArgs:  q.Args,
+					ExpectedIndex: nextExpected,
+					ActualIndex:   -1,
+				})
+			}
+		}
+		for ; nextActual < actualEnd; nextActual++ {
+			if q := actual[nextActual]; q.Ignored {
+				differences = append(differences, Difference{
+					Type:          DiffIgnored,
+					Actual:        q.SQL,
+					ActualArgs:    q.Args,
+					ExpectedIndex: -1,
+					ActualIndex:   nextActual,
+				})
+			}
+		}
+	}
+
+	for _, diff := range result.Differences {
+		expectedEnd, actualEnd := nextExpected, nextActual
+		if diff.ExpectedIndex >= 0 {
+			diff.ExpectedIndex = expectedIndexes[diff.ExpectedIndex]
+			expectedEnd = max(expectedEnd, diff.ExpectedIndex)
+		}
+		if diff.ActualIndex >= 0 {
+			diff.ActualIndex = actualIndexes[diff.ActualIndex]
+			actualEnd = max(actualEnd, diff.ActualIndex)
+		}
+		flush(expectedEnd, actualEnd)
+		differences = append(differences, diff)
+	}
+	flush(len(expected), len(actual))
+
+	for i := range differences {
+		differences[i].Index = i
+	}
+	result.Differences = differences
+	return result
+}
+
+// compareOrdered compares queries in order.
+// The sequences are aligned on their longest common subsequence of queries with
+// equal SQL, so a single inserted or removed query is reported as one EXTRA or
+// MISSING entry while the queries around it still match. Among alignments of
+// the same length, the one with the most exact matches (equal arguments) is
+// chosen, so repeated queries are paired with the query binding the same
+// arguments rather than the first one with the same SQL.
+// With pairGaps set, queries left between aligned pairs are paired in order
+// as MODIFIED, and any surplus on either side is reported as EXTRA or MISSING.
+// Otherwise every unaligned query is reported as EXTRA or MISSING.
+func (c *Comparator) compareOrdered(expected, actual []Query, pairGaps bool) CompareResult {
 	result := CompareResult{
 		Equal:       true,
 		Differences: make([]Difference, 0),
 	}
 
-	maxLen := max(len(actual), len(expected))
+	// pairs[i][j] holds the comparison of expected[i] with actual[j]
+	pairs := make([][]Difference, len(expected))
+	for i, e := range expected {
+		pairs[i] = make([]Difference, len(actual))
+		for j, a := range actual {
+			pairs[i][j] = c.pair(i, j, e, a)
+		}
+	}
+	sameSQL := func(i, j int) bool {
+		return pairs[i][j].Type != DiffModified
+	}
 
-	for i := 0; i < maxLen; i++ {
-		var diff Difference
-		diff.Index = i
+	// An aligned pair scores more than any number of exact matches, and an
+	// exact match one more than an argument mismatch
+	pairScore := min(len(expected), len(actual)) + 1
+	score := func(i, j int) int {
+		if pairs[i][j].Type == DiffMatch {
+			return pairScore + 1
+		}
+		return pairScore
+	}
 
-		if i >= len(expected) {
-			// Extra query in actual
-			diff.Type = DiffExtra
-			diff.Actual = actual[i]
-			result.Equal = false
-		} else if i >= len(actual) {
-			// Missing query in actual
-			diff.Type = DiffMissing
-			diff.Expected = expected[i]
-			result.Equal = false
-		} else if expected[i] != actual[i] {
-			// Modified query
-			diff.Type = DiffModified
-			diff.Expected = expected[i]
-			diff.Actual = actual[i]
-			result.Equal = false
-		} else {
-			// Match
-			diff.Type = DiffMatch
-			diff.Expected = expected[i]
-			diff.Actual = actual[i]
+	// lcs[i][j] is the best score of a common subsequence of expected[i:] and actual[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			if sameSQL(i, j) {
+				lcs[i][j] = max(lcs[i][j], lcs[i+1][j+1]+score(i, j))
+			}
 		}
+	}
 
+	add := func(diff Difference) {
+		diff.Index = len(result.Differences)
+		if diff.Type != DiffMatch {
+			result.Equal = false
+		}
 		result.Differences = append(result.Differences, diff)
 	}
 
+	// flush reports the unaligned queries between two aligned pairs
+	flush := func(gapExpected, gapActual []int) {
+		if !pairGaps {
+			for _, i := range gapExpected {
+				add(missing(i, expected[i]))
+			}
+			for _, j := range gapActual {
+				add(extra(j, actual[j]))
+			}
+			return
+		}
+		for k := 0; k < max(len(gapExpected), len(gapActual)); k++ {
+			switch {
+			case k >= len(gapExpected):
+				add(extra(gapActual[k], actual[gapActual[k]]))
+			case k >= len(gapActual):
+				add(missing(gapExpected[k], expected[gapExpected[k]]))
+			default:
+				add(pairs[gapExpected[k]][gapActual[k]])
+			}
+		}
+	}
+
+	var gapExpected, gapActual []int
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && sameSQL(i, j) && lcs[i][j] == lcs[i+1][j+1]+score(i, j):
+			flush(gapExpected, gapActual)
+			gapExpected, gapActual = nil, nil
+			add(pairs[i][j])
+			i++
+			j++
+		case j >= len(actual) || (i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]):
+			gapExpected = append(gapExpected, i)
+			i++
+		default:
+			gapActual = append(gapActual, j)
+			j++
+		}
+	}
+	flush(gapExpected, gapActual)
+
 	return result
 }
 
-// compareUnordered compares queries as sets.
-func (c *Comparator) compareUnordered(expected, actual []string) CompareResult {
+// missing returns the difference for an expected query without an actual counterpart.
+func missing(index int, expected Query) Difference {
+	return Difference{
+		Type:          DiffMissing,
+		Expected:      expected.SQL,
+		ExpectedArgs:  expected.Args,
+		ExpectedIndex: index,
+		ActualIndex:   -1,
+	}
+}
+
+// extra returns the difference for an actual query without an expected counterpart.
+func extra(index int, actual Query) Difference {
+	return Difference{
+		Type:          DiffExtra,
+		Actual:        actual.SQL,
+		ActualArgs:    actual.Args,
+		ExpectedIndex: -1,
+		ActualIndex:   index,
+	}
+}
+
+// pair compares an expected query with the actual query it is paired with.
+// The caller sets the Index of the returned difference.
+func (c *Comparator) pair(expectedIndex, actualIndex int, expected, actual Query) Difference {
+	diff := Difference{
+		Type:          DiffMatch,
+		Expected:      expected.SQL,
+		Actual:        actual.SQL,
+		ExpectedArgs:  expected.Args,
+		ActualArgs:    actual.Args,
+		Matchers:      expected.Matchers,
+		ExpectedIndex: expectedIndex,
+		ActualIndex:   actualIndex,
+	}
+
+	if c.options.Structural && expected.Statement != nil && actual.Statement != nil {
+		result := ast.Compare(expected.Statement, actual.Statement, c.options.Equivalences)
+		if !result.Equal() {
+			diff.Type = DiffModified
+			diff.Details = result.Differences
+			return diff
+		}
+		// Equivalent statements may bind their arguments in a different order
+		diff.ActualArgs = alignArgs(result.ArgMap, len(expected.Args), actual.Args)
+	} else if expected.SQL != actual.SQL {
+		diff.Type = DiffModified
+		return diff
+	}
+
+	if c.options.CompareArgs && !argsEqual(diff.ExpectedArgs, diff.ActualArgs, diff.Matchers, c.options.UnorderedLists) {
+		diff.Type = DiffArgs
+	}
+
+	return diff
+}
+
+// alignArgs reorders actual arguments to line up with the expected arguments
+// they are mapped to. The arguments are returned unchanged if the mapping is
+// not a one-to-one correspondence.
+func alignArgs(argMap map[int]int, expectedCount int, actual []any) []any {
+	if len(argMap) != expectedCount || expectedCount != len(actual) {
+		return actual
+	}
+
+	aligned := make([]any, expectedCount)
+	seen := make([]bool, len(actual))
+	for e, a := range argMap {
+		if e < 0 || e >= expectedCount || a < 0 || a >= len(actual) || seen[a] {
+			return actual
+		}
+		seen[a] = true
+		aligned[e] = actual[a]
+	}
+	return aligned
+}
+
+// compareUnordered compares queries as multisets.
+// Queries that only differ in their bind arguments are paired as DiffArgs.
+func (c *Comparator) compareUnordered(expected, actual []Query) CompareResult {
 	result := CompareResult{
 		Equal:       true,
 		Differences: make([]Difference, 0),
 	}
 
-	// Build a map of expected queries
-	expectedMap := make(map[string]int)
-	for _, q := range expected {
-		expectedMap[q]++
+	// Pair queries that match exactly first, so that argument
+	// mismatches are only reported for queries without an exact match
+	pairedWith := make([]int, len(expected))
+	used := make([]bool, len(actual))
+	for i, e := range expected {
+		pairedWith[i] = -1
+		for j, a := range actual {
+			if !used[j] && c.pair(i, j, e, a).Type == DiffMatch {
+				pairedWith[i] = j
+				used[j] = true
+				break
+			}
+		}
 	}
-
-	// Build a map of actual queries
-	actualMap := make(map[string]int)
-	for _, q := range actual {
-		actualMap[q]++
+	for i, e := range expected {
+		if pairedWith[i] >= 0 {
+			continue
+		}
+		for j, a := range actual {
+			if !used[j] && c.pair(i, j, e, a).Type == DiffArgs {
+				pairedWith[i] = j
+				used[j] = true
+				break
+			}
+		}
 	}
 
-	// Find missing queries (in expected but not in actual)
+	// Report expected queries in order (matched, argument mismatch or missing)
 	idx := 0
-	for _, q := range expected {
-		if actualMap[q] <= 0 {
-			result.Differences = append(result.Differences, Difference{
-				Type:     DiffMissing,
-				Index:    idx,
-				Expected: q,
-			})
-			result.Equal = false
+	for i, e := range expected {
+		var diff Difference
+		if j := pairedWith[i]; j >= 0 {
+			diff = c.pair(i, j, e, actual[j])
 		} else {
-			result.Differences = append(result.Differences, Difference{
-				Type:     DiffMatch,
-				Index:    idx,
-				Expected: q,
-				Actual:   q,
-			})
-			actualMap[q]--
+			diff = missing(i, e)
 		}
+		diff.Index = idx
+		if diff.Type != DiffMatch {
+			result.Equal = false
+		}
+		result.Differences = append(result.Differences, diff)
 		idx++
 	}
 
 	// Find extra queries (in actual but not in expected)
-	for _, q := range actual {
-		if expectedMap[q] <= 0 {
-			result.Differences = append(result.Differences, Difference{
-				Type:   DiffExtra,
-				Index:  idx,
-				Actual: q,
-			})
-			result.Equal = false
-			idx++
-		} else {
-			expectedMap[q]--
+	for j, a := range actual {
+		if used[j] {
+			continue
 		}
+		diff := extra(j, a)
+		diff.Index = idx
+		result.Differences = append(result.Differences, diff)
+		result.Equal = false
+		idx++
 	}
 
 	return result
 }
 
 // FormatDifferences formats the differences as a human-readable string.
-func FormatDifferences(result CompareResult, expectedCount, actualCount int) string {
+// MODIFIED entries include a token-level diff of the expected and actual queries.
+func FormatDifferences(result CompareResult, expectedCount, actualCount int, opts FormatOptions) string {
 	var sb strings.Builder
 
 	sb.WriteString("migratiorm: queries do not match\n\n")
@@ -188,21 +540,76 @@ func FormatDifferences(result CompareResult, expectedCount, actualCount int) str
 	sb.WriteString("Differences:\n")
 
 	for _, diff := range result.Differences {
-		switch diff.Type {
-		case DiffMatch:
-			sb.WriteString(fmt.Sprintf("  [%d] OK: %s\n", diff.Index, diff.Expected))
-		case DiffMissing:
-			sb.WriteString(fmt.Sprintf("  [%d] MISSING:\n", diff.Index))
-			sb.WriteString(fmt.Sprintf("      expected: %s\n", diff.Expected))
-		case DiffExtra:
-			sb.WriteString(fmt.Sprintf("  [%d] EXTRA:\n", diff.Index))
-			sb.WriteString(fmt.Sprintf("      actual:   %s\n", diff.Actual))
-		case DiffModified:
-			sb.WriteString(fmt.Sprintf("  [%d] MODIFIED:\n", diff.Index))
-			sb.WriteString(fmt.Sprintf("      expected: %s\n", diff.Expected))
-			sb.WriteString(fmt.Sprintf("      actual:   %s\n", diff.Actual))
-		}
+		formatDifference(&sb, diff, opts)
 	}
 
 	return sb.String()
 }
+
+// FormatAccepted formats the accepted differences of a result, or returns ""
+// if there are none.
+func FormatAccepted(result CompareResult, opts FormatOptions) string {
+	return formatType(result, DiffAccepted, "migratiorm: queries match with accepted differences", opts)
+}
+
+// FormatAdded formats the queries a subset mode allowed in addition to the
+// expected ones, or returns "" if there are none.
+func FormatAdded(result CompareResult, opts FormatOptions) string {
+	return formatType(result, DiffAdded, "migratiorm: queries match with added queries", opts)
+}
+
+// formatType formats the differences of a type under header, or returns ""
+// if there are none.
+func formatType(result CompareResult, diffType DiffType, header string, opts FormatOptions) string {
+	var sb strings.Builder
+	for _, diff := range result.Differences {
+		if diff.Type == diffType {
+			formatDifference(&sb, diff, opts)
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return header + "\n\n" + sb.String()
+}
+
+// formatDifference writes a single difference.
+func formatDifference(sb *strings.Builder, diff Difference, opts FormatOptions) {
+	switch diff.Type {
+	case DiffMatch:
+		sb.WriteString(formatSQL(diff.Expected, fmt.Sprintf("  [%d] OK: ", diff.Index), opts) + "\n")
+	case DiffMissing:
+		sb.WriteString(fmt.Sprintf("  [%d] MISSING:\n", diff.Index))
+		sb.WriteString(formatSQL(diff.Expected, "      expected: ", opts) + "\n")
+	case DiffExtra:
+		sb.WriteString(fmt.Sprintf("  [%d] EXTRA:\n", diff.Index))
+		sb.WriteString(formatSQL(diff.Actual, "      actual:   ", opts) + "\n")
+	case DiffModified:
+		sb.WriteString(fmt.Sprintf("  [%d] MODIFIED:\n", diff.Index))
+		sb.WriteString(formatSQL(diff.Expected, "      expected: ", opts) + "\n")
+		sb.WriteString(formatSQL(diff.Actual, "      actual:   ", opts) + "\n")
+		sb.WriteString(formatTokenDiff(diff.Expected, diff.Actual, "      diff:     ", opts) + "\n")
+		for _, detail := range diff.Details {
+			sb.WriteString(fmt.Sprintf("      - %s\n", detail))
+		}
+		for _, line := range diff.Trace {
+			sb.WriteString("      " + line + "\n")
+		}
+	case DiffIgnored:
+		sb.WriteString(formatIgnored(diff, opts) + "\n")
+	case DiffAdded:
+		sb.WriteString(formatSQL(diff.Actual, fmt.Sprintf("  [%d] ADDED: ", diff.Index), opts) + "\n")
+	case DiffAccepted:
+		sb.WriteString(fmt.Sprintf("  [%d] ACCEPTED: %s\n", diff.Index, diff.Reason))
+		if diff.ExpectedIndex >= 0 {
+			sb.WriteString(formatSQL(diff.Expected, "      expected: ", opts) + "\n")
+		}
+		if diff.ActualIndex >= 0 {
+			sb.WriteString(formatSQL(diff.Actual, "      actual:   ", opts) + "\n")
+		}
+	case DiffArgs:
+		sb.WriteString(formatSQL(diff.Expected, fmt.Sprintf("  [%d] ARGS: ", diff.Index), opts) + "\n")
+		sb.WriteString(fmt.Sprintf("      expected args: %s\n", formatExpectedArgs(diff.ExpectedArgs, diff.Matchers)))
+		sb.WriteString(fmt.Sprintf("      actual args:   %s\n", FormatArgs(diff.ActualArgs)))
+	}
+}
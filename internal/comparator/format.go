@@ -0,0 +1,246 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatOptions controls how differences are formatted.
+type FormatOptions struct {
+	Color  bool // Highlight changed tokens with ANSI colors instead of plain markers
+	Pretty bool // Print queries across multiple lines, one clause per line
+
+	// Tokenize splits a query into lexical tokens, each starting with a single
+	// space if it is preceded by whitespace (default: split at whitespace)
+	Tokenize func(sql string) []string
+}
+
+// ANSI escape sequences used to highlight changed tokens.
+const (
+	ansiRemoved = "\x1b[31m" // Red
+	ansiAdded   = "\x1b[32m" // Green
+	ansiDim     = "\x1b[2m"
+	ansiReset   = "\x1b[0m"
+)
+
+// formatSQL formats a query for a line starting with prefix.
+// In pretty mode, each clause starts a new line aligned after prefix.
+func formatSQL(sql, prefix string, opts FormatOptions) string {
+	if !opts.Pretty {
+		return prefix + sql
+	}
+
+	tokens := splitTokens(sql, opts)
+	indent := strings.Repeat(" ", len(prefix))
+	lines := make([]string, 0)
+	for _, line := range clauseLines(trimTokens(tokens), nil) {
+		lines = append(lines, strings.TrimLeft(strings.Join(tokens[line[0]:line[1]], ""), " "))
+	}
+	return prefix + strings.Join(lines, "\n"+indent)
+}
+
+// splitTokens splits a query into tokens with opts.Tokenize, or at
+// whitespace if it is not set.
+func splitTokens(sql string, opts FormatOptions) []string {
+	if opts.Tokenize != nil {
+		return opts.Tokenize(sql)
+	}
+	tokens := strings.Fields(sql)
+	for i := 1; i < len(tokens); i++ {
+		tokens[i] = " " + tokens[i]
+	}
+	return tokens
+}
+
+// trimTokens returns the tokens without their leading spaces.
+func trimTokens(tokens []string) []string {
+	result := make([]string, len(tokens))
+	for i, t := range tokens {
+		result[i] = strings.TrimLeft(t, " ")
+	}
+	return result
+}
+
+// formatIgnored formats an ignored query, dimmed in color mode.
+func formatIgnored(diff Difference, opts FormatOptions) string {
+	side, sql := "actual", diff.Actual
+	if diff.ExpectedIndex >= 0 {
+		side, sql = "expected", diff.Expected
+	}
+	line := formatSQL(sql, fmt.Sprintf("  [%d] IGNORED (%s): ", diff.Index, side), opts)
+	if opts.Color {
+		return ansiDim + line + ansiReset
+	}
+	return line
+}
+
+// tokenOp is a token of a token-level diff.
+type tokenOp struct {
+	token string // Token text, starting with a space if preceded by whitespace
+	op    byte   // ' ' for common tokens, '-' for removed tokens, '+' for inserted tokens
+}
+
+// tokenDiff computes a token-level diff from expected to actual using the
+// longest common subsequence of their tokens. Tokens are equal regardless of
+// the whitespace before them.
+func tokenDiff(expected, actual []string) []tokenOp {
+	e, a := trimTokens(expected), trimTokens(actual)
+
+	lcs := make([][]int, len(e)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(a)+1)
+	}
+	for i := len(e) - 1; i >= 0; i-- {
+		for j := len(a) - 1; j >= 0; j-- {
+			if e[i] == a[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []tokenOp
+	i, j := 0, 0
+	for i < len(e) || j < len(a) {
+		switch {
+		case i < len(e) && j < len(a) && e[i] == a[j]:
+			ops = append(ops, tokenOp{token: actual[j], op: ' '})
+			i++
+			j++
+		case j >= len(a) || (i < len(e) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, tokenOp{token: expected[i], op: '-'})
+			i++
+		default:
+			ops = append(ops, tokenOp{token: actual[j], op: '+'})
+			j++
+		}
+	}
+	return ops
+}
+
+// formatTokenDiff formats a token-level diff for a line starting with prefix.
+// Removed and inserted tokens are highlighted in red and green, or marked as
+// [-removed-] and {+inserted+} without colors.
+func formatTokenDiff(expected, actual, prefix string, opts FormatOptions) string {
+	ops := tokenDiff(splitTokens(expected, opts), splitTokens(actual, opts))
+
+	ranges := [][2]int{{0, len(ops)}}
+	if opts.Pretty {
+		tokens := make([]string, len(ops))
+		removed := make([]bool, len(ops))
+		for i, op := range ops {
+			tokens[i] = strings.TrimLeft(op.token, " ")
+			removed[i] = op.op == '-'
+		}
+		// Nesting follows the actual query, so that removed parentheses do not unbalance it
+		ranges = clauseLines(tokens, removed)
+	}
+
+	lines := make([]string, len(ranges))
+	for i, r := range ranges {
+		lines[i] = strings.TrimLeft(formatTokenOps(ops[r[0]:r[1]], opts.Color), " ")
+	}
+	return prefix + strings.Join(lines, "\n"+strings.Repeat(" ", len(prefix)))
+}
+
+// formatTokenOps renders a run of token operations, grouping consecutive
+// removed and inserted tokens.
+func formatTokenOps(ops []tokenOp, color bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].op == ' ' {
+			sb.WriteString(ops[i].token)
+			i++
+			continue
+		}
+
+		var removed, added []string
+		for i < len(ops) && ops[i].op != ' ' {
+			if ops[i].op == '-' {
+				removed = append(removed, ops[i].token)
+			} else {
+				added = append(added, ops[i].token)
+			}
+			i++
+		}
+		if len(removed) > 0 {
+			sb.WriteString(highlightTokens(removed, "[-", "-]", ansiRemoved, color))
+		}
+		if len(added) > 0 {
+			sb.WriteString(highlightTokens(added, "{+", "+}", ansiAdded, color))
+		}
+	}
+	return sb.String()
+}
+
+// highlightTokens highlights a run of tokens, keeping the space before the
+// first token outside of the highlighting.
+func highlightTokens(tokens []string, startMarker, endMarker, ansi string, color bool) string {
+	text := strings.Join(tokens, "")
+	space := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	return space + highlight(text[len(space):], startMarker, endMarker, ansi, color)
+}
+
+// highlight wraps text in ANSI colors or plain markers.
+func highlight(text, startMarker, endMarker, ansi string, color bool) string {
+	if color {
+		return ansi + text + ansiReset
+	}
+	return startMarker + text + endMarker
+}
+
+// clauseLines splits tokens into lines that each start with a clause keyword
+// outside of parentheses. Tokens marked in skip do not change the nesting depth.
+// It returns the [start, end) token range of each line.
+func clauseLines(tokens []string, skip []bool) [][2]int {
+	var lines [][2]int
+	start, depth := 0, 0
+	for i, token := range tokens {
+		if i > start && depth == 0 && startsClause(tokens, i) {
+			lines = append(lines, [2]int{start, i})
+			start = i
+		}
+		if skip == nil || !skip[i] {
+			depth += strings.Count(token, "(") - strings.Count(token, ")")
+		}
+	}
+	return append(lines, [2]int{start, len(tokens)})
+}
+
+// startsClause reports whether the token at i starts a clause.
+func startsClause(tokens []string, i int) bool {
+	token := strings.ToUpper(tokens[i])
+	next := ""
+	if i+1 < len(tokens) {
+		next = strings.ToUpper(tokens[i+1])
+	}
+	prev := ""
+	if i > 0 {
+		prev = strings.ToUpper(tokens[i-1])
+	}
+
+	switch token {
+	case "FROM", "WHERE", "HAVING", "LIMIT", "OFFSET", "FETCH", "RETURNING",
+		"SET", "VALUES", "UNION", "INTERSECT", "EXCEPT", "WINDOW":
+		// IS [NOT] DISTINCT FROM is an operator
+		return token != "FROM" || prev != "DISTINCT"
+	case "GROUP", "ORDER":
+		return next == "BY"
+	case "ON":
+		return next == "CONFLICT" || next == "DUPLICATE"
+	case "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL":
+		if prev == "NATURAL" {
+			return false
+		}
+		return next == "JOIN" || next == "OUTER" || next == "INNER" ||
+			next == "LEFT" || next == "RIGHT" || next == "FULL"
+	case "JOIN", "STRAIGHT_JOIN":
+		switch prev {
+		case "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL", "OUTER":
+			return false
+		}
+		return true
+	}
+	return false
+}
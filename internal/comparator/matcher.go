@@ -0,0 +1,111 @@
+package comparator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// ArgMatcher decides whether an actual bind argument matches the expected one.
+// Matchers are used for non-deterministic values such as timestamps and generated IDs.
+type ArgMatcher interface {
+	// Match reports whether the expected and actual arguments are considered equal.
+	Match(expected, actual any) bool
+	// String describes the matcher in difference reports.
+	String() string
+}
+
+// AnyArg returns a matcher that accepts any pair of arguments.
+func AnyArg() ArgMatcher {
+	return anyArg{}
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(expected, actual any) bool { return true }
+func (anyArg) String() string                  { return "<any>" }
+
+// AnyArgOfType returns a matcher that accepts arguments of the same type as sample.
+// Types are compared after coercion, so AnyArgOfType(0) also accepts int64 values.
+func AnyArgOfType(sample any) ArgMatcher {
+	return anyArgOfType{typ: reflect.TypeOf(coerceValue(sample))}
+}
+
+type anyArgOfType struct {
+	typ reflect.Type
+}
+
+func (m anyArgOfType) Match(expected, actual any) bool {
+	return reflect.TypeOf(coerceValue(expected)) == m.typ && reflect.TypeOf(coerceValue(actual)) == m.typ
+}
+
+func (m anyArgOfType) String() string {
+	return fmt.Sprintf("<any %v>", m.typ)
+}
+
+// TimeWithin returns a matcher that accepts two times at most tolerance apart.
+func TimeWithin(tolerance time.Duration) ArgMatcher {
+	return timeWithin{tolerance: tolerance}
+}
+
+type timeWithin struct {
+	tolerance time.Duration
+}
+
+func (m timeWithin) Match(expected, actual any) bool {
+	e, ok := expected.(time.Time)
+	if !ok {
+		return false
+	}
+	a, ok := actual.(time.Time)
+	if !ok {
+		return false
+	}
+	d := e.Sub(a)
+	if d < 0 {
+		d = -d
+	}
+	return d <= m.tolerance
+}
+
+func (m timeWithin) String() string {
+	return fmt.Sprintf("<time within %v>", m.tolerance)
+}
+
+// MatchRegexp returns a matcher that accepts string arguments matching re on both sides.
+func MatchRegexp(re *regexp.Regexp) ArgMatcher {
+	return regexpMatcher{re: re}
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexpMatcher) Match(expected, actual any) bool {
+	e, ok := coerceValue(expected).(string)
+	if !ok {
+		return false
+	}
+	a, ok := coerceValue(actual).(string)
+	if !ok {
+		return false
+	}
+	return m.re.MatchString(e) && m.re.MatchString(a)
+}
+
+func (m regexpMatcher) String() string {
+	return fmt.Sprintf("<match /%s/>", m.re)
+}
+
+// MatchFunc returns a matcher backed by a custom function.
+func MatchFunc(fn func(expected, actual any) bool) ArgMatcher {
+	return funcMatcher{fn: fn}
+}
+
+type funcMatcher struct {
+	fn func(expected, actual any) bool
+}
+
+func (m funcMatcher) Match(expected, actual any) bool { return m.fn(expected, actual) }
+func (m funcMatcher) String() string                  { return "<func>" }
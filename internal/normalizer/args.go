@@ -0,0 +1,252 @@
+package normalizer
+
+// argsMappable reports whether every placeholder refers to an existing argument
+// and every argument is referenced by at least one placeholder.
+func argsMappable(tokens []token, argCount int) bool {
+	referenced := make([]bool, argCount)
+	for _, t := range tokens {
+		if t.kind != tokenPlaceholder {
+			continue
+		}
+		if t.arg < 0 || t.arg >= argCount {
+			return false
+		}
+		referenced[t.arg] = true
+	}
+	for _, r := range referenced {
+		if !r {
+			return false
+		}
+	}
+	return true
+}
+
+// argsInRange reports whether every placeholder refers to an existing argument.
+// Rewrite rules may add placeholders without an argument.
+func argsInRange(tokens []token, argCount int) bool {
+	for _, t := range tokens {
+		if t.kind == tokenPlaceholder && !t.argInRange(argCount) {
+			return false
+		}
+	}
+	return true
+}
+
+// argInRange reports whether the placeholder t refers to existing arguments.
+func (t token) argInRange(argCount int) bool {
+	if t.group != nil {
+		return argsInRange(t.group, argCount)
+	}
+	return t.bound || (t.arg >= 0 && t.arg < argCount)
+}
+
+// placeholderArgs returns the arguments referenced by the placeholders, in placeholder order.
+// The arguments of a collapsed IN list are returned as a single []any value.
+func placeholderArgs(tokens []token, args []any) []any {
+	var result []any
+	for _, t := range tokens {
+		if t.kind == tokenPlaceholder {
+			result = append(result, t.argValue(args))
+		}
+	}
+	return result
+}
+
+// argValue returns the argument of the placeholder t.
+func (t token) argValue(args []any) any {
+	switch {
+	case t.group != nil:
+		return placeholderArgs(t.group, args)
+	case t.bound:
+		return t.value
+	default:
+		return args[t.arg]
+	}
+}
+
+// collapseInLists collapses IN lists made of placeholders only into a single
+// placeholder, so that batches of different sizes compare equal. The
+// placeholder refers to the arguments of the whole list, which are returned
+// by NormalizeArgs as one []any value.
+// WHERE id IN (?, ?, ?) → WHERE id IN (?)
+// Lists containing literals, expressions or subqueries are left unchanged.
+func collapseInLists(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		result = append(result, t)
+		if !t.is("IN") || i+1 >= len(tokens) || !tokens[i+1].isPunct("(") {
+			continue
+		}
+
+		open := i + 1
+		closeIdx := matchingParen(tokens, open)
+		if closeIdx < 0 || !isPlaceholderList(tokens[open+1:closeIdx]) {
+			continue
+		}
+
+		placeholder := tokens[open+1]
+		placeholder.group = nil
+		for _, p := range tokens[open+1 : closeIdx] {
+			switch {
+			case p.kind != tokenPlaceholder:
+			case p.group != nil:
+				placeholder.group = append(placeholder.group, p.group...)
+			default:
+				placeholder.group = append(placeholder.group, p)
+			}
+		}
+		result = append(result, tokens[open], placeholder, tokens[closeIdx])
+		i = closeIdx
+	}
+	return result
+}
+
+// isPlaceholderList reports whether tokens are one or more comma-separated placeholders.
+func isPlaceholderList(tokens []token) bool {
+	if len(tokens)%2 == 0 {
+		return false
+	}
+	for i, t := range tokens {
+		if (i%2 == 0 && t.kind != tokenPlaceholder) || (i%2 == 1 && !t.isPunct(",")) {
+			return false
+		}
+	}
+	return true
+}
+
+// PlaceholderColumns returns the column each placeholder of a normalized query
+// is bound to, in placeholder order. Placeholders that are not compared to a
+// column (LIMIT ?, function arguments, ...) have an empty column name.
+// Table qualifiers are stripped, so users.age is reported as age.
+// The query is tokenized with the normalizer's dialect; unified ? placeholders
+// are recognized in every dialect.
+func (n *Normalizer) PlaceholderColumns(query string) []string {
+	tokens := n.normalizedTokens(query)
+	insertColumns := insertPlaceholderColumns(tokens)
+
+	var columns []string
+	for i, t := range tokens {
+		if t.kind != tokenPlaceholder {
+			continue
+		}
+		col, ok := insertColumns[i]
+		if !ok {
+			col = comparedColumn(tokens, i)
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// comparedColumn returns the column the placeholder at i is compared to:
+// col = ?, col LIKE ?, col IN (?, ?), col BETWEEN ? AND ?.
+func comparedColumn(tokens []token, i int) string {
+	j := i - 1
+
+	// col [NOT] IN (..., ?)
+	for k := j; k >= 0; k-- {
+		if tokens[k].isPunct(",") || tokens[k].kind == tokenPlaceholder {
+			continue
+		}
+		if tokens[k].isPunct("(") && k > 0 && tokens[k-1].is("IN") {
+			return columnBefore(tokens, skipNot(tokens, k-2))
+		}
+		break
+	}
+
+	// col [NOT] BETWEEN ? AND ?
+	if j >= 2 && tokens[j].is("AND") && tokens[j-1].kind == tokenPlaceholder && tokens[j-2].is("BETWEEN") {
+		j -= 2
+	}
+	if j >= 0 && tokens[j].is("BETWEEN") {
+		return columnBefore(tokens, skipNot(tokens, j-1))
+	}
+
+	// col = ?, col [NOT] [I]LIKE ?
+	if j >= 0 && (tokens[j].is("LIKE") || tokens[j].is("ILIKE")) {
+		return columnBefore(tokens, skipNot(tokens, j-1))
+	}
+	if j >= 0 && tokens[j].kind == tokenOperator {
+		switch tokens[j].text {
+		case "=", "<>", "!=", "<=", ">=", "<", ">":
+			return columnBefore(tokens, j-1)
+		}
+	}
+	return ""
+}
+
+// skipNot returns the index before an optional NOT keyword at i.
+func skipNot(tokens []token, i int) int {
+	if i >= 0 && tokens[i].is("NOT") {
+		return i - 1
+	}
+	return i
+}
+
+// columnBefore returns the unqualified column name ending at i, or "".
+func columnBefore(tokens []token, i int) string {
+	if i < 0 || !tokens[i].isName() {
+		return ""
+	}
+	return tokens[i].name()
+}
+
+// insertPlaceholderColumns maps the indexes of placeholders in INSERT value
+// tuples to the column at the same position in the column list.
+func insertPlaceholderColumns(tokens []token) map[int]string {
+	result := make(map[int]string)
+
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i].is("INSERT") || !tokens[i+1].is("INTO") {
+			continue
+		}
+
+		open := scanName(tokens, i+2)
+		if open >= len(tokens) || !tokens[open].isPunct("(") {
+			continue
+		}
+		closeIdx := matchingParen(tokens, open)
+		if closeIdx < 0 || closeIdx+1 >= len(tokens) || !tokens[closeIdx+1].is("VALUES") {
+			continue
+		}
+
+		var columns []string
+		for _, col := range splitTopLevel(tokens[open+1 : closeIdx]) {
+			name := ""
+			if len(col) > 0 {
+				name = col[len(col)-1].name()
+			}
+			columns = append(columns, name)
+		}
+
+		// Walk the value tuples, tracking the position within the current tuple
+		depth, position := 0, 0
+	values:
+		for k := closeIdx + 2; k < len(tokens); k++ {
+			t := tokens[k]
+			switch {
+			case t.isPunct("("):
+				depth++
+				if depth == 1 {
+					position = 0
+				}
+			case t.isPunct(")"):
+				depth--
+				if depth < 0 {
+					break values
+				}
+			case t.isPunct(","):
+				if depth == 1 {
+					position++
+				}
+			case depth == 0:
+				// End of the VALUES list (ON CONFLICT, RETURNING, ...)
+				break values
+			case depth == 1 && t.kind == tokenPlaceholder && position < len(columns):
+				result[k] = columns[position]
+			}
+		}
+	}
+	return result
+}
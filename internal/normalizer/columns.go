@@ -1,206 +1,237 @@
 package normalizer
 
 import (
-	"regexp"
 	"sort"
-	"strings"
 )
 
 // normalizeSelectColumns normalizes SELECT column lists to *.
 // This enables semantic comparison where "SELECT *" and "SELECT id, name" are considered equivalent.
-func normalizeSelectColumns(query string) string {
-	// Match SELECT ... FROM pattern and replace the column list with *
-	// This handles:
-	// - SELECT * FROM ...
-	// - SELECT id, name, email FROM ...
-	// - SELECT users.id, users.name FROM ...
-	// - SELECT DISTINCT id, name FROM ...
-	//
-	// Note: This is a simplified implementation that may not handle all edge cases
-	// such as subqueries in SELECT clause, CASE expressions, etc.
-
-	// Pattern explanation:
-	// SELECT\s+ - SELECT keyword followed by whitespace
-	// (DISTINCT\s+)? - optional DISTINCT keyword
-	// .+? - column list (non-greedy)
-	// \s+FROM\b - whitespace followed by FROM keyword
-	re := regexp.MustCompile(`(?i)(SELECT\s+)(DISTINCT\s+)?(.+?)(\s+FROM\b)`)
-
-	return re.ReplaceAllStringFunc(query, func(match string) string {
-		submatches := re.FindStringSubmatch(match)
-		if len(submatches) < 5 {
-			return match
+// This handles:
+// - SELECT * FROM ...
+// - SELECT id, name, email FROM ...
+// - SELECT users.id, users.name FROM ...
+// - SELECT DISTINCT id, name FROM ...
+// - SELECT (SELECT COUNT(*) FROM orders) AS n FROM users (subqueries in the column list)
+//
+// SELECT statements without FROM (SELECT 1) are left unchanged.
+func normalizeSelectColumns(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		result = append(result, t)
+		if !t.is("SELECT") {
+			continue
 		}
 
-		selectPart := submatches[1]   // "SELECT "
-		distinctPart := submatches[2] // "DISTINCT " or ""
-		// submatches[3] is the column list - we replace this with *
-		fromPart := submatches[4] // " FROM"
+		// Keep optional DISTINCT/ALL keyword
+		start := i + 1
+		if start < len(tokens) && (tokens[start].is("DISTINCT") || tokens[start].is("ALL")) {
+			result = append(result, tokens[start])
+			start++
+		}
 
-		return selectPart + distinctPart + "*" + fromPart
-	})
+		// Find FROM at the same nesting level as SELECT
+		from := clauseEnd(tokens, start, func(t token) bool { return t.is("FROM") })
+		if from >= len(tokens) || !tokens[from].is("FROM") {
+			i = start - 1
+			continue
+		}
+
+		result = append(result, newToken(tokenOperator, "*", true))
+		i = from - 1
+	}
+	return result
 }
 
 // sortInsertColumns sorts the column order in INSERT statements for comparison.
 // INSERT INTO t (c, b, a) VALUES (?, ?, ?) → INSERT INTO t (a, b, c) VALUES (?, ?, ?)
-func sortInsertColumns(query string) string {
-	// Match INSERT INTO table (columns) VALUES (values) pattern
-	re := regexp.MustCompile(`(?i)(INSERT\s+INTO\s+\w+\s*)\(([^)]+)\)(\s*VALUES\s*)\(([^)]+)\)`)
-
-	return re.ReplaceAllStringFunc(query, func(match string) string {
-		submatches := re.FindStringSubmatch(match)
-		if len(submatches) < 5 {
-			return match
+// Every row of a multi-row VALUES list is reordered the same way.
+func sortInsertColumns(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].is("INSERT") {
+			if sorted, end, ok := sortInsertStatement(tokens, i); ok {
+				result = append(result, sorted...)
+				i = end - 1
+				continue
+			}
 		}
+		result = append(result, tokens[i])
+	}
+	return result
+}
 
-		insertPart := submatches[1]  // "INSERT INTO table "
-		columnsPart := submatches[2] // "c, b, a"
-		valuesPart := submatches[3]  // " VALUES "
-		valuesData := submatches[4]  // "?, ?, ?"
+// sortInsertStatement sorts the INSERT statement starting at start.
+// It returns the rewritten tokens up to end, the index after the last VALUES row.
+func sortInsertStatement(tokens []token, start int) ([]token, int, bool) {
+	// INSERT INTO table (columns)
+	i := start + 1
+	if i >= len(tokens) || !tokens[i].is("INTO") {
+		return nil, 0, false
+	}
+	i = scanName(tokens, i+1)
+	if i >= len(tokens) || !tokens[i].isPunct("(") {
+		return nil, 0, false
+	}
+	columnsOpen := i
+	columnsClose := matchingParen(tokens, columnsOpen)
+	if columnsClose < 0 {
+		return nil, 0, false
+	}
+	columns := splitTopLevel(tokens[columnsOpen+1 : columnsClose])
 
-		// Parse columns
-		columns := strings.Split(columnsPart, ",")
-		for i := range columns {
-			columns[i] = strings.TrimSpace(columns[i])
-		}
+	// VALUES (row), (row), ...
+	i = columnsClose + 1
+	if i >= len(tokens) || !(tokens[i].is("VALUES") || tokens[i].is("VALUE")) {
+		return nil, 0, false
+	}
+	valuesKeyword := i
+	i++
 
-		// Parse values
-		values := strings.Split(valuesData, ",")
-		for i := range values {
-			values[i] = strings.TrimSpace(values[i])
+	type row struct {
+		open   token
+		close  token
+		values [][]token
+	}
+	var rows []row
+	for i < len(tokens) && tokens[i].isPunct("(") {
+		closeIdx := matchingParen(tokens, i)
+		if closeIdx < 0 {
+			return nil, 0, false
 		}
-
+		values := splitTopLevel(tokens[i+1 : closeIdx])
 		// If column count doesn't match value count, return original
-		if len(columns) != len(values) {
-			return match
-		}
-
-		// Create column-value pairs
-		type pair struct {
-			column string
-			value  string
+		if len(values) != len(columns) {
+			return nil, 0, false
 		}
-		pairs := make([]pair, len(columns))
-		for i := range columns {
-			pairs[i] = pair{column: columns[i], value: values[i]}
+		rows = append(rows, row{open: tokens[i], close: tokens[closeIdx], values: values})
+		i = closeIdx + 1
+		if i+1 < len(tokens) && tokens[i].isPunct(",") && tokens[i+1].isPunct("(") {
+			i++
+			continue
 		}
+		break
+	}
+	if len(rows) == 0 {
+		return nil, 0, false
+	}
+	end := i
 
-		// Sort by column name
-		sort.Slice(pairs, func(i, j int) bool {
-			return pairs[i].column < pairs[j].column
-		})
-
-		// Rebuild
-		sortedColumns := make([]string, len(pairs))
-		sortedValues := make([]string, len(pairs))
-		for i, p := range pairs {
-			sortedColumns[i] = p.column
-			sortedValues[i] = p.value
+	// Sort by column name, carrying values along
+	order := make([]int, len(columns))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return renderKey(columns[order[a]]) < renderKey(columns[order[b]])
+	})
+
+	// Rebuild
+	result := append([]token{}, tokens[start:columnsOpen+1]...)
+	result = append(result, joinList(permute(columns, order), false)...)
+	result = append(result, tokens[columnsClose], tokens[valuesKeyword])
+	for k, r := range rows {
+		if k > 0 {
+			result = append(result, newToken(tokenPunct, ",", false))
 		}
+		result = append(result, r.open)
+		result = append(result, joinList(permute(r.values, order), false)...)
+		result = append(result, r.close)
+	}
 
-		return insertPart + "(" + strings.Join(sortedColumns, ", ") + ")" +
-			valuesPart + "(" + strings.Join(sortedValues, ", ") + ")"
-	})
+	return result, end, true
 }
 
-// sortUpdateColumns sorts the SET column order in UPDATE statements for comparison.
-// UPDATE t SET c = ?, b = ?, a = ? WHERE ... → UPDATE t SET a = ?, b = ?, c = ? WHERE ...
-func sortUpdateColumns(query string) string {
-	// First try to match UPDATE with WHERE clause
-	reWithWhere := regexp.MustCompile(`(?i)(UPDATE\s+\w+\s+SET\s+)(.+?)(\s+WHERE\b.*)`)
-	if matches := reWithWhere.FindStringSubmatch(query); len(matches) >= 4 {
-		return sortUpdateSetClause(matches[1], matches[2], matches[3])
+// permute returns items reordered by order.
+func permute(items [][]token, order []int) [][]token {
+	result := make([][]token, len(order))
+	for i, idx := range order {
+		result[i] = items[idx]
 	}
+	return result
+}
 
-	// Try to match UPDATE without WHERE clause
-	reNoWhere := regexp.MustCompile(`(?i)(UPDATE\s+\w+\s+SET\s+)(.+)$`)
-	if matches := reNoWhere.FindStringSubmatch(query); len(matches) >= 3 {
-		return sortUpdateSetClause(matches[1], matches[2], "")
-	}
+// sortUpdateColumns sorts the SET column order in UPDATE statements for comparison.
+// UPDATE t SET c = ?, b = ?, a = ? WHERE ... → UPDATE t SET a = ?, b = ?, c = ? WHERE ...
+func sortUpdateColumns(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		result = append(result, t)
+		if !t.is("SET") || !isUpdateSet(tokens, i) {
+			continue
+		}
 
-	return query
-}
+		end := clauseEnd(tokens, i+1, isClauseKeyword)
+		assignments := parseSetAssignments(tokens[i+1 : end])
+		if len(assignments) == 0 {
+			continue
+		}
 
-// sortUpdateSetClause sorts the SET clause assignments.
-func sortUpdateSetClause(prefix, setClause, suffix string) string {
-	// Parse SET assignments (column = value pairs)
-	// This is a simplified parser that handles basic cases
-	assignments := parseSetAssignments(setClause)
-	if len(assignments) == 0 {
-		return prefix + setClause + suffix
+		sortAssignments(assignments)
+		result = append(result, joinAssignments(assignments)...)
+		i = end - 1
 	}
+	return result
+}
 
-	// Sort by column name
-	sort.Slice(assignments, func(i, j int) bool {
-		return assignments[i].column < assignments[j].column
+// sortAssignments sorts assignments by column name.
+func sortAssignments(assignments []assignment) {
+	sort.SliceStable(assignments, func(a, b int) bool {
+		return renderKey(assignments[a].column) < renderKey(assignments[b].column)
 	})
+}
 
-	// Rebuild
-	parts := make([]string, len(assignments))
-	for i, a := range assignments {
-		parts[i] = a.column + " = " + a.value
+// joinAssignments joins assignments as "col1 = val1, col2 = val2".
+func joinAssignments(assignments []assignment) []token {
+	var result []token
+	for k, a := range assignments {
+		if k > 0 {
+			result = append(result, newToken(tokenPunct, ",", false))
+		}
+		result = append(result, withSpace(a.column, true)...)
+		result = append(result, newToken(tokenOperator, "=", true))
+		result = append(result, withSpace(a.value, true)...)
 	}
+	return result
+}
 
-	return prefix + strings.Join(parts, ", ") + suffix
+// isUpdateSet reports whether the SET keyword at i belongs to an UPDATE statement
+// (UPDATE table [alias] SET), as opposed to e.g. SET NAMES.
+func isUpdateSet(tokens []token, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch {
+		case tokens[j].is("UPDATE"):
+			return true
+		case !tokens[j].isName() && !tokens[j].isPunct("."):
+			return false
+		}
+	}
+	return false
 }
 
 // assignment represents a column = value pair in SET clause.
 type assignment struct {
-	column string
-	value  string
+	column []token
+	value  []token
 }
 
 // parseSetAssignments parses "col1 = val1, col2 = val2" into assignments.
-func parseSetAssignments(setClause string) []assignment {
+// Commas inside function calls and subqueries are not treated as separators.
+func parseSetAssignments(tokens []token) []assignment {
 	var result []assignment
-
-	// Split by comma, but we need to be careful about commas inside function calls
-	// For simplicity, we'll use a basic split that works for common cases
-	parts := splitSetClause(setClause)
-
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		eqIdx := strings.Index(part, "=")
-		if eqIdx == -1 {
-			return nil // Invalid format
-		}
-		col := strings.TrimSpace(part[:eqIdx])
-		val := strings.TrimSpace(part[eqIdx+1:])
-		result = append(result, assignment{column: col, value: val})
-	}
-
-	return result
-}
-
-// splitSetClause splits SET clause by comma, respecting parentheses.
-func splitSetClause(s string) []string {
-	var result []string
-	var current strings.Builder
-	depth := 0
-
-	for _, ch := range s {
-		switch ch {
-		case '(':
-			depth++
-			current.WriteRune(ch)
-		case ')':
-			depth--
-			current.WriteRune(ch)
-		case ',':
-			if depth == 0 {
-				result = append(result, current.String())
-				current.Reset()
-			} else {
-				current.WriteRune(ch)
+	for _, part := range splitTopLevel(tokens) {
+		eqIdx := -1
+		for k, t := range part {
+			if t.isOperator("=") {
+				eqIdx = k
+				break
 			}
-		default:
-			current.WriteRune(ch)
 		}
+		if eqIdx <= 0 || eqIdx == len(part)-1 {
+			return nil // Invalid format
+		}
+		result = append(result, assignment{column: part[:eqIdx], value: part[eqIdx+1:]})
 	}
-
-	if current.Len() > 0 {
-		result = append(result, current.String())
-	}
-
 	return result
 }
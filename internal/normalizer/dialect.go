@@ -0,0 +1,155 @@
+package normalizer
+
+// Dialect identifies the SQL dialect of the normalized queries.
+// It determines how queries are tokenized: which characters quote identifiers
+// and strings, which placeholder syntaxes exist, whether unquoted identifiers
+// are case-insensitive, and which dialect keywords are uppercased.
+type Dialect int
+
+const (
+	// DialectGeneric accepts the syntax of all supported databases (default).
+	// Backticks, double quotes and brackets quote identifiers, and ?, $1, :name
+	// and @name are placeholders. Unquoted identifiers keep their case.
+	DialectGeneric Dialect = iota
+	// DialectMySQL follows MySQL: backticks quote identifiers, single and double
+	// quotes delimit strings with backslash escapes, # starts a comment, ? is the
+	// only placeholder and @var is a user variable. Identifiers keep their case,
+	// since table names are case-sensitive on most platforms.
+	DialectMySQL
+	// DialectPostgreSQL follows PostgreSQL: double quotes quote identifiers,
+	// $1 is the only placeholder (? is a JSON operator), $tag$ quotes strings,
+	// and unquoted identifiers are folded to lower case.
+	DialectPostgreSQL
+	// DialectSQLite follows SQLite: double quotes, backticks and brackets quote
+	// identifiers, ?, ?1, :name, @name and $name are placeholders, and unquoted
+	// identifiers are case-insensitive.
+	DialectSQLite
+	// DialectSQLServer follows SQL Server: brackets and double quotes quote
+	// identifiers, @name and @p1 are placeholders, @@name is a system variable,
+	// and unquoted identifiers are case-insensitive.
+	DialectSQLServer
+)
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case DialectGeneric:
+		return "Generic"
+	case DialectMySQL:
+		return "MySQL"
+	case DialectPostgreSQL:
+		return "PostgreSQL"
+	case DialectSQLite:
+		return "SQLite"
+	case DialectSQLServer:
+		return "SQLServer"
+	default:
+		return "Unknown"
+	}
+}
+
+// placeholderSyntax is a set of placeholder syntaxes recognized by a dialect.
+type placeholderSyntax uint8
+
+const (
+	placeholderQuestion      placeholderSyntax = 1 << iota // ?
+	placeholderQuestionIndex                               // ?1 (argument 1)
+	placeholderDollarIndex                                 // $1 (argument 1)
+	placeholderColonName                                   // :name
+	placeholderAtName                                      // @name
+	placeholderAtIndex                                     // @p1 (argument 1)
+	placeholderDollarName                                  // $name
+)
+
+// dialectSpec describes the lexical rules of a dialect.
+type dialectSpec struct {
+	identQuotes      string // Characters opening quoted identifiers
+	stringQuotes     string // Characters opening string literals
+	backslashEscapes bool   // Backslash escapes characters in all string literals
+	dollarQuotes     bool   // $tag$...$tag$ strings
+	hashComments     bool   // # starts a line comment
+	placeholders     placeholderSyntax
+	foldLower        bool            // Unquoted identifiers are case-insensitive
+	keywords         map[string]bool // Keywords to uppercase, including sqlKeywords
+}
+
+// has reports whether the dialect recognizes the placeholder syntax p.
+func (s dialectSpec) has(p placeholderSyntax) bool {
+	return s.placeholders&p != 0
+}
+
+// dialectSpecs maps each dialect to its lexical rules.
+var dialectSpecs = map[Dialect]dialectSpec{
+	DialectGeneric: {
+		identQuotes:  "\"`[",
+		stringQuotes: "'",
+		dollarQuotes: true,
+		placeholders: placeholderQuestion | placeholderDollarIndex | placeholderColonName | placeholderAtName,
+		keywords:     keywords(),
+	},
+	DialectMySQL: {
+		identQuotes:      "`",
+		stringQuotes:     "'\"",
+		backslashEscapes: true,
+		hashComments:     true,
+		placeholders:     placeholderQuestion,
+		keywords: keywords(
+			"REPLACE", "IGNORE", "DUPLICATE", "STRAIGHT_JOIN", "SQL_CALC_FOUND_ROWS",
+			"LOCK", "SHARE", "REGEXP", "RLIKE", "DIV", "XOR", "INTERVAL", "FOR",
+			"USE", "FORCE", "HIGH_PRIORITY", "LOW_PRIORITY", "DELAYED",
+		),
+	},
+	DialectPostgreSQL: {
+		identQuotes:  "\"",
+		stringQuotes: "'",
+		dollarQuotes: true,
+		placeholders: placeholderDollarIndex,
+		foldLower:    true,
+		keywords: keywords(
+			"ILIKE", "SIMILAR", "CONFLICT", "DO", "NOTHING", "EXCLUDED", "LATERAL",
+			"ONLY", "USING", "WITH", "RECURSIVE", "FILTER", "OVER", "PARTITION",
+			"FETCH", "NEXT", "ROWS", "NULLS", "ANY", "ARRAY", "FOR", "SKIP", "LOCKED", "NOWAIT",
+		),
+	},
+	DialectSQLite: {
+		identQuotes:  "\"`[",
+		stringQuotes: "'",
+		placeholders: placeholderQuestion | placeholderQuestionIndex | placeholderColonName |
+			placeholderAtName | placeholderDollarName,
+		foldLower: true,
+		keywords: keywords(
+			"GLOB", "REPLACE", "IGNORE", "CONFLICT", "DO", "NOTHING", "EXCLUDED",
+			"ABORT", "FAIL", "WITH", "RECURSIVE", "USING", "PRAGMA",
+		),
+	},
+	DialectSQLServer: {
+		identQuotes:  "[\"",
+		stringQuotes: "'",
+		placeholders: placeholderAtName | placeholderAtIndex,
+		foldLower:    true,
+		keywords: keywords(
+			"TOP", "PERCENT", "TIES", "OUTPUT", "INSERTED", "DELETED", "MERGE", "USING",
+			"MATCHED", "WITH", "NOLOCK", "FETCH", "NEXT", "ROWS", "ONLY",
+		),
+	},
+}
+
+// spec returns the lexical rules of the dialect, falling back to DialectGeneric.
+func (d Dialect) spec() dialectSpec {
+	if s, ok := dialectSpecs[d]; ok {
+		return s
+	}
+	return dialectSpecs[DialectGeneric]
+}
+
+// keywords returns a set of sqlKeywords and the given dialect keywords.
+func keywords(extra ...string) map[string]bool {
+	set := make(map[string]bool, len(sqlKeywords)+len(extra))
+	for _, kw := range sqlKeywords {
+		set[kw] = true
+	}
+	for _, kw := range extra {
+		set[kw] = true
+	}
+	return set
+}
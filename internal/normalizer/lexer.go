@@ -0,0 +1,398 @@
+package normalizer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// tokenKind represents the lexical category of a token.
+type tokenKind int
+
+const (
+	tokenIdent       tokenKind = iota // Unquoted identifier or keyword
+	tokenQuotedIdent                  // Quoted identifier ("x", `x`, [x])
+	tokenString                       // String literal ('x', E'x', $$x$$)
+	tokenNumber                       // Numeric literal
+	tokenPlaceholder                  // Bind placeholder (?, $1, :name, @name)
+	tokenComment                      // Comment (-- x, /* x */)
+	tokenPunct                        // Punctuation: ( ) , ; .
+	tokenOperator                     // Operator (=, <>, ::, ...)
+)
+
+// token is a lexical unit of a SQL query.
+type token struct {
+	kind  tokenKind
+	text  string  // Source text of the token
+	space bool    // Whether the token is preceded by whitespace
+	arg   int     // Index of the bind argument for placeholders, -1 otherwise
+	group []token // Placeholders of a collapsed IN list, nil otherwise
+	value any     // Value of a placeholder lifted from an inline literal
+	bound bool    // Whether value holds the argument of the placeholder instead of arg
+}
+
+// multiCharOperators lists operators made of several characters, longest first.
+var multiCharOperators = []string{
+	"<=>", "->>", "#>>",
+	"<=", ">=", "<>", "!=", "==", "::", ":=", "=>", "||", "->", "#>", "<<", ">>",
+}
+
+// tokenize splits a SQL query into tokens following the lexical rules of spec.
+// Whitespace is not emitted as tokens but recorded in the space flag of the following token.
+// Placeholders are numbered in order of appearance, except indexed placeholders
+// such as $1 which refer to the argument with that number.
+func tokenize(query string, spec dialectSpec) []token {
+	l := lexer{src: query, spec: spec}
+	return l.run()
+}
+
+// lexer holds the state of a tokenize call.
+type lexer struct {
+	src      string
+	spec     dialectSpec
+	pos      int
+	tokens   []token
+	space    bool
+	position int // Next sequential placeholder index
+}
+
+func (l *lexer) run() []token {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case isSpace(c):
+			l.space = true
+			l.pos++
+		case c == '-' && l.peek(1) == '-':
+			l.lineComment()
+		case c == '#' && l.spec.hashComments:
+			l.lineComment()
+		case c == '/' && l.peek(1) == '*':
+			l.blockComment()
+		case strings.IndexByte(l.spec.stringQuotes, c) >= 0:
+			l.quoted(tokenString, c, l.pos, l.spec.backslashEscapes)
+		case isStringPrefix(c) && l.peek(1) == '\'' && !l.afterIdentChar():
+			// E'x' strings interpret backslash escapes in PostgreSQL
+			escapes := l.spec.backslashEscapes || c == 'E' || c == 'e'
+			l.pos++
+			l.quoted(tokenString, '\'', l.pos-1, escapes)
+		case c == '[' && strings.IndexByte(l.spec.identQuotes, c) >= 0:
+			l.quoted(tokenQuotedIdent, ']', l.pos, false)
+		case strings.IndexByte(l.spec.identQuotes, c) >= 0:
+			l.quoted(tokenQuotedIdent, c, l.pos, false)
+		case c == '$':
+			l.dollar()
+		case c == '?':
+			l.question()
+		case c == ':' && isIdentStart(l.peek(1)) && l.spec.has(placeholderColonName):
+			l.placeholder(l.scanIdent(l.pos + 1))
+		case c == '@' && l.peek(1) == '@':
+			l.emit(tokenIdent, l.scanIdent(l.pos+2), -1)
+		case c == '@' && isIdentStart(l.peek(1)):
+			l.at()
+		case isDigit(c) || (c == '.' && isDigit(l.peek(1))):
+			l.number()
+		case isIdentStart(c):
+			l.emit(tokenIdent, l.scanIdent(l.pos), -1)
+		case c == '(' || c == ')' || c == ',' || c == ';' || c == '.':
+			l.emit(tokenPunct, l.pos+1, -1)
+		default:
+			l.operator()
+		}
+	}
+	return l.tokens
+}
+
+// emit appends the token spanning from the current position to end.
+func (l *lexer) emit(kind tokenKind, end, arg int) {
+	l.tokens = append(l.tokens, token{
+		kind:  kind,
+		text:  l.src[l.pos:end],
+		space: l.space,
+		arg:   arg,
+	})
+	l.pos = end
+	l.space = false
+}
+
+// emitFrom appends a token spanning from start to end.
+func (l *lexer) emitFrom(kind tokenKind, start, end int) {
+	l.pos = start
+	l.emit(kind, end, -1)
+}
+
+// peek returns the byte at offset from the current position, or 0.
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+// afterIdentChar reports whether the current position directly follows an identifier character.
+func (l *lexer) afterIdentChar() bool {
+	return l.pos > 0 && isIdentChar(l.src[l.pos-1])
+}
+
+// scanIdent returns the end of the identifier starting at start.
+func (l *lexer) scanIdent(start int) int {
+	end := start
+	for end < len(l.src) && isIdentChar(l.src[end]) {
+		end++
+	}
+	return end
+}
+
+func (l *lexer) lineComment() {
+	end := strings.IndexByte(l.src[l.pos:], '\n')
+	if end < 0 {
+		end = len(l.src)
+	} else {
+		end += l.pos
+	}
+	l.emit(tokenComment, end, -1)
+}
+
+func (l *lexer) blockComment() {
+	end := strings.Index(l.src[l.pos+2:], "*/")
+	if end < 0 {
+		end = len(l.src)
+	} else {
+		end += l.pos + 4
+	}
+	l.emit(tokenComment, end, -1)
+}
+
+// quoted scans a quoted token whose closing quote is closer.
+// A doubled closing quote is an escaped quote, as is a backslash-escaped
+// character when escapes is set. start is the start of the token, which
+// precedes the opening quote for prefixed strings such as E'x'.
+func (l *lexer) quoted(kind tokenKind, closer byte, start int, escapes bool) {
+	i := l.pos + 1
+	for i < len(l.src) {
+		if escapes && l.src[i] == '\\' {
+			i += 2
+			continue
+		}
+		if l.src[i] == closer {
+			if i+1 < len(l.src) && l.src[i+1] == closer {
+				i += 2
+				continue
+			}
+			i++
+			break
+		}
+		i++
+	}
+	l.emitFrom(kind, start, min(i, len(l.src)))
+}
+
+// dollar scans $1 and $name placeholders and $tag$...$tag$ strings.
+func (l *lexer) dollar() {
+	if isDigit(l.peek(1)) && l.spec.has(placeholderDollarIndex) {
+		l.indexed(l.pos + 1)
+		return
+	}
+
+	// Dollar-quoted string: $tag$ ... $tag$
+	if l.spec.dollarQuotes {
+		tagEnd := l.pos + 1
+		for tagEnd < len(l.src) && isIdentChar(l.src[tagEnd]) && l.src[tagEnd] != '$' {
+			tagEnd++
+		}
+		if tagEnd < len(l.src) && l.src[tagEnd] == '$' {
+			tag := l.src[l.pos : tagEnd+1]
+			if end := strings.Index(l.src[tagEnd+1:], tag); end >= 0 {
+				l.emit(tokenString, tagEnd+1+end+len(tag), -1)
+				return
+			}
+		}
+	}
+
+	if isIdentStart(l.peek(1)) && l.spec.has(placeholderDollarName) {
+		l.placeholder(l.scanIdent(l.pos + 1))
+		return
+	}
+
+	l.operator()
+}
+
+// question scans ? and ?1 placeholders, or ? operators in dialects without them.
+func (l *lexer) question() {
+	switch {
+	case isDigit(l.peek(1)) && l.spec.has(placeholderQuestionIndex):
+		l.indexed(l.pos + 1)
+	case l.spec.has(placeholderQuestion):
+		l.placeholder(l.pos + 1)
+	default:
+		l.operator()
+	}
+}
+
+// at scans @name and @p1 placeholders, or @var variables in dialects without them.
+func (l *lexer) at() {
+	end := l.scanIdent(l.pos + 1)
+	switch {
+	case l.spec.has(placeholderAtIndex) && isIndexName(l.src[l.pos+1:end]):
+		l.indexed(l.pos + 2)
+	case l.spec.has(placeholderAtName):
+		l.placeholder(end)
+	default:
+		l.emit(tokenIdent, end, -1)
+	}
+}
+
+// indexed emits a placeholder whose argument number starts at digits.
+func (l *lexer) indexed(digits int) {
+	end := digits
+	for end < len(l.src) && isDigit(l.src[end]) {
+		end++
+	}
+	n, _ := strconv.Atoi(l.src[digits:end])
+	l.emit(tokenPlaceholder, end, n-1)
+}
+
+// placeholder emits a sequentially numbered placeholder ending at end.
+func (l *lexer) placeholder(end int) {
+	l.emit(tokenPlaceholder, end, l.position)
+	l.position++
+}
+
+func (l *lexer) number() {
+	end := l.pos
+	if l.src[end] == '0' && (l.peek(1) == 'x' || l.peek(1) == 'X') {
+		end += 2
+		for end < len(l.src) && isHexDigit(l.src[end]) {
+			end++
+		}
+		l.emit(tokenNumber, end, -1)
+		return
+	}
+
+	for end < len(l.src) && isDigit(l.src[end]) {
+		end++
+	}
+	if end < len(l.src) && l.src[end] == '.' {
+		end++
+		for end < len(l.src) && isDigit(l.src[end]) {
+			end++
+		}
+	}
+	if end < len(l.src) && (l.src[end] == 'e' || l.src[end] == 'E') {
+		exp := end + 1
+		if exp < len(l.src) && (l.src[exp] == '+' || l.src[exp] == '-') {
+			exp++
+		}
+		if exp < len(l.src) && isDigit(l.src[exp]) {
+			end = exp
+			for end < len(l.src) && isDigit(l.src[end]) {
+				end++
+			}
+		}
+	}
+	l.emit(tokenNumber, end, -1)
+}
+
+func (l *lexer) operator() {
+	for _, op := range multiCharOperators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.emit(tokenOperator, l.pos+len(op), -1)
+			return
+		}
+	}
+	l.emit(tokenOperator, l.pos+1, -1)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+// isIndexName reports whether name is an ordinal parameter name such as p1.
+func isIndexName(name string) bool {
+	if len(name) < 2 || (name[0] != 'p' && name[0] != 'P') {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isDigit(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isStringPrefix reports whether c can prefix a string literal (E'x', N'x', X'x', B'x').
+func isStringPrefix(c byte) bool {
+	switch c {
+	case 'E', 'e', 'N', 'n', 'X', 'x', 'B', 'b':
+		return true
+	}
+	return false
+}
+
+// render joins tokens back into a query, separating tokens by a single space
+// wherever the source had whitespace.
+func render(tokens []token) string {
+	var sb strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.space {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// name returns the identifier name of an identifier token without quotes.
+func (t token) name() string {
+	if t.kind != tokenQuotedIdent || len(t.text) < 2 {
+		return t.text
+	}
+	switch t.text[0] {
+	case '"', '`':
+		if t.text[len(t.text)-1] == t.text[0] {
+			q := t.text[:1]
+			return strings.ReplaceAll(t.text[1:len(t.text)-1], q+q, q)
+		}
+	case '[':
+		if t.text[len(t.text)-1] == ']' {
+			return strings.ReplaceAll(t.text[1:len(t.text)-1], "]]", "]")
+		}
+	}
+	return t.text
+}
+
+// isName reports whether the token is an identifier, quoted or not.
+func (t token) isName() bool {
+	return t.kind == tokenIdent || t.kind == tokenQuotedIdent
+}
+
+// is reports whether the token is the unquoted keyword kw (case-insensitive).
+func (t token) is(kw string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, kw)
+}
+
+// isPunct reports whether the token is the punctuation p.
+func (t token) isPunct(p string) bool {
+	return t.kind == tokenPunct && t.text == p
+}
+
+// isOperator reports whether the token is the operator op.
+func (t token) isOperator(op string) bool {
+	return t.kind == tokenOperator && t.text == op
+}
@@ -0,0 +1,162 @@
+package normalizer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parameterizeLiterals replaces numeric, string and boolean literals with
+// placeholders bound to the literal values, so that queries with inlined
+// values compare equal to queries binding the same values.
+// WHERE age > 18 AND name = 'Alice' → WHERE age > ? AND name = ? (args: 18, "Alice")
+// Literals that are not values are kept: ORDER BY 1, GROUP BY 1, typed
+// literals such as DATE '2024-01-01', hexadecimal and bit strings, and NULL.
+func parameterizeLiterals(tokens []token, spec dialectSpec) []token {
+	result := make([]token, 0, len(tokens))
+	positional := false // Within an ORDER BY or GROUP BY list
+	for i, t := range tokens {
+		switch {
+		case t.is("ORDER") || t.is("GROUP"):
+			positional = i+1 < len(tokens) && tokens[i+1].is("BY")
+		case isClauseKeyword(t) || t.isPunct("("):
+			positional = false
+		}
+
+		value, ok := literalValue(t, spec)
+		if !ok || (positional && t.kind == tokenNumber) || isTypedLiteral(tokens, i) {
+			result = append(result, t)
+			continue
+		}
+
+		placeholder := token{kind: tokenPlaceholder, text: "?", space: t.space, arg: -1, value: value, bound: true}
+
+		// A unary minus belongs to the number: x = -1 → x = ?
+		if t.kind == tokenNumber && isUnaryMinus(tokens, i-1, spec.keywords) {
+			result = result[:len(result)-1]
+			placeholder.space = tokens[i-1].space
+			switch v := value.(type) {
+			case int64:
+				placeholder.value = -v
+			case float64:
+				placeholder.value = -v
+			}
+		}
+		result = append(result, placeholder)
+	}
+	return result
+}
+
+// literalValue returns the value of a numeric, string or boolean literal.
+func literalValue(t token, spec dialectSpec) (any, bool) {
+	switch t.kind {
+	case tokenNumber:
+		if strings.HasPrefix(t.text, "0x") || strings.HasPrefix(t.text, "0X") {
+			return nil, false
+		}
+		if v, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return v, true
+		}
+		if v, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return v, true
+		}
+	case tokenString:
+		return stringValue(t.text, spec.backslashEscapes)
+	case tokenIdent:
+		switch {
+		case t.is("TRUE"):
+			return true, true
+		case t.is("FALSE"):
+			return false, true
+		}
+	}
+	return nil, false
+}
+
+// stringValue returns the value of a string literal: 'x', "x" where double
+// quotes delimit strings, E'x', N'x' and $tag$x$tag$.
+func stringValue(text string, backslashEscapes bool) (any, bool) {
+	if strings.HasPrefix(text, "$") {
+		tagEnd := strings.IndexByte(text[1:], '$') + 2
+		if tagEnd < 2 || len(text) < 2*tagEnd {
+			return nil, false
+		}
+		return text[tagEnd : len(text)-tagEnd], true
+	}
+
+	switch text[0] {
+	case 'E', 'e':
+		backslashEscapes = true
+		text = text[1:]
+	case 'N', 'n':
+		text = text[1:]
+	}
+	if len(text) < 2 || (text[0] != '\'' && text[0] != '"') || text[len(text)-1] != text[0] {
+		return nil, false
+	}
+
+	quote := text[0]
+	body := text[1 : len(text)-1]
+	var sb strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == quote && i+1 < len(body) && body[i+1] == quote:
+			i++
+		case c == '\\' && backslashEscapes && i+1 < len(body):
+			i++
+			c = unescape(body[i])
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), true
+}
+
+// unescape returns the character of a backslash escape sequence.
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case '0':
+		return 0
+	}
+	return c
+}
+
+// isTypedLiteral reports whether the literal at i is the string of a typed
+// literal, such as DATE '2024-01-01' or INTERVAL '1 day'.
+func isTypedLiteral(tokens []token, i int) bool {
+	if i == 0 || tokens[i].kind != tokenString {
+		return false
+	}
+	for _, kw := range []string{"DATE", "TIME", "TIMESTAMP", "INTERVAL"} {
+		if tokens[i-1].is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnaryMinus reports whether the token at i is a minus sign applied to the
+// following operand rather than a subtraction.
+func isUnaryMinus(tokens []token, i int, keywords map[string]bool) bool {
+	if i < 0 || !tokens[i].isOperator("-") {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	prev := tokens[i-1]
+	switch prev.kind {
+	case tokenOperator:
+		return true
+	case tokenPunct:
+		return prev.isPunct("(") || prev.isPunct(",")
+	case tokenIdent:
+		return keywords[strings.ToUpper(prev.text)] && !prev.is("NULL") && !prev.is("TRUE") && !prev.is("FALSE")
+	}
+	return false
+}
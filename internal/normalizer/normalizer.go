@@ -1,6 +1,7 @@
 package normalizer
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -15,8 +16,40 @@ type Options struct {
 	NormalizeOrderByAsc      bool // Remove redundant ASC in ORDER BY (default: false)
 	SortInsertColumns        bool // Sort INSERT column order for comparison (default: false)
 	SortUpdateColumns        bool // Sort UPDATE SET column order for comparison (default: false)
+	SortPredicates           bool // Sort AND/OR predicates in WHERE, HAVING and ON conditions (default: false)
 	RemoveReturningClause    bool // Remove RETURNING clause from INSERT/UPDATE/DELETE (default: false)
 	NormalizeTableQualifiers bool // Remove redundant table qualifiers in simple queries (default: false)
+	RemoveRedundantParens    bool // Unwrap parenthesized predicates: WHERE (a = ?) -> WHERE a = ? (default: false)
+	NormalizeSoftDeletes     bool // Move deleted_at IS NULL predicates added by ORMs to the end of their condition (default: false)
+	RemovePrimaryKeyLimit    bool // Remove ORDER BY col LIMIT 1 when WHERE fixes col, or LIMIT 1 when it fixes id (default: false)
+	CollapseInLists          bool // Collapse IN (?, ?, ?) to IN (?) with the arguments grouped as one list (default: false)
+	ParameterizeLiterals     bool // Replace inline literals with placeholders bound to their values (default: false)
+	NormalizePagination      bool // Rewrite LIMIT a, b, OFFSET ... FETCH and TOP to LIMIT ... OFFSET (default: false)
+	NormalizeUpserts         bool // Rewrite upserts to ON CONFLICT ... DO UPDATE SET with sorted assignments (default: false)
+	SpaceOperators           bool // Surround comparison operators with spaces: id=? -> id = ? (default: false)
+
+	Dialect  Dialect   // SQL dialect of the queries (default: DialectGeneric)
+	Rewrites []Rewrite // User-defined rewrite rules, in registration order within a stage
+}
+
+// Stage is a position in the normalization pipeline where rewrite rules run.
+type Stage int
+
+const (
+	// StageRaw runs rewrite rules on the raw query before any built-in step.
+	StageRaw Stage = iota
+	// StageLexical runs rewrite rules after comments, quotes, placeholders and
+	// keyword case are normalized, before the structural steps.
+	StageLexical
+	// StageFinal runs rewrite rules on the normalized query after all built-in steps.
+	StageFinal
+)
+
+// Rewrite is a user-defined transformation of the query text.
+// Bind arguments follow the placeholders of the rewritten query in order.
+type Rewrite struct {
+	Stage Stage
+	Apply func(query string) string
 }
 
 // DefaultOptions returns the default normalizer options.
@@ -31,20 +64,32 @@ func DefaultOptions() Options {
 		NormalizeOrderByAsc:      false,
 		SortInsertColumns:        false,
 		SortUpdateColumns:        false,
+		SortPredicates:           false,
 		RemoveReturningClause:    false,
 		NormalizeTableQualifiers: false,
+		RemoveRedundantParens:    false,
+		NormalizeSoftDeletes:     false,
+		RemovePrimaryKeyLimit:    false,
+		CollapseInLists:          false,
+		ParameterizeLiterals:     false,
+		NormalizePagination:      false,
+		NormalizeUpserts:         false,
+		SpaceOperators:           false,
+		Dialect:                  DialectGeneric,
 	}
 }
 
 // Normalizer normalizes SQL queries for comparison.
 type Normalizer struct {
 	options Options
+	spec    dialectSpec
 }
 
 // New creates a new Normalizer with the given options.
 func New(opts Options) *Normalizer {
 	return &Normalizer{
 		options: opts,
+		spec:    opts.Dialect.spec(),
 	}
 }
 
@@ -55,57 +100,216 @@ func NewDefault() *Normalizer {
 
 // Normalize normalizes a SQL query string.
 func (n *Normalizer) Normalize(query string) string {
-	result := query
+	result, _ := n.NormalizeArgs(query, nil)
+	return result
+}
 
-	if n.options.RemoveComments {
-		result = removeComments(result)
+// NormalizeArgs normalizes a SQL query string together with its bind arguments.
+// The returned arguments follow the order of the placeholders in the normalized query,
+// so that transformations which reorder placeholders keep arguments aligned.
+// If the placeholders cannot be mapped to the arguments, the arguments are returned unchanged.
+func (n *Normalizer) NormalizeArgs(query string, args []any) (string, []any) {
+	for _, r := range n.options.Rewrites {
+		if r.Stage == StageRaw {
+			query = r.Apply(query)
+		}
 	}
 
-	// Handle quotes and keywords together to preserve quoted identifier case
-	if n.options.RemoveQuotes && n.options.UppercaseKeywords {
-		result = removeQuotesPreservingCase(result)
-	} else if n.options.RemoveQuotes {
-		result = removeQuotes(result)
-	}
+	tokens := tokenize(query, n.spec)
+	mappable := argsMappable(tokens, len(args))
 
-	if n.options.UnifyPlaceholders {
-		result = unifyPlaceholders(result)
+	for _, s := range n.steps() {
+		tokens = s.apply(tokens)
 	}
 
-	result = normalizeWhitespace(result)
+	result := strings.TrimSpace(render(tokens))
+	if !mappable || !argsInRange(tokens, len(args)) {
+		return result, args
+	}
+	return result, placeholderArgs(tokens, args)
+}
 
-	// Only uppercase if we haven't already done it with quote removal
-	if n.options.UppercaseKeywords && !n.options.RemoveQuotes {
-		result = uppercaseKeywords(result)
+// Tokens splits a query into its lexical tokens without normalizing it.
+// A token preceded by whitespace starts with a single space, so that joining
+// the tokens renders the query with single spaces.
+func (n *Normalizer) Tokens(query string) []string {
+	tokens := tokenize(query, n.spec)
+	result := make([]string, len(tokens))
+	for i, t := range tokens {
+		result[i] = t.text
+		if i > 0 && t.space {
+			result[i] = " " + t.text
+		}
 	}
+	return result
+}
+
+// TraceStep is a step of the normalization pipeline with the query before and after it.
+type TraceStep struct {
+	Name   string // Step name, e.g. RemoveQuotes or Rewrite[0] for a rewrite rule
+	Input  string // Query before the step
+	Output string // Query after the step
+}
 
-	if n.options.NormalizeSelectColumns {
-		result = normalizeSelectColumns(result)
+// Explain normalizes a query like Normalize and returns every step of the
+// pipeline in the order it ran, including steps that left the query unchanged.
+// The Output of the last step is the normalized query.
+func (n *Normalizer) Explain(query string) []TraceStep {
+	var trace []TraceStep
+	for i, r := range n.options.Rewrites {
+		if r.Stage == StageRaw {
+			input := query
+			query = r.Apply(query)
+			trace = append(trace, TraceStep{Name: fmt.Sprintf("Rewrite[%d]", i), Input: input, Output: query})
+		}
 	}
 
-	if n.options.NormalizeJoinSyntax {
-		result = normalizeJoinSyntax(result)
+	tokens := tokenize(query, n.spec)
+	for _, s := range n.steps() {
+		input := strings.TrimSpace(render(tokens))
+		tokens = s.apply(tokens)
+		trace = append(trace, TraceStep{Name: s.name, Input: input, Output: strings.TrimSpace(render(tokens))})
 	}
+	return trace
+}
+
+// step is a single named transformation of the normalization pipeline.
+type step struct {
+	name  string
+	apply func([]token) []token
+}
 
-	if n.options.NormalizeOrderByAsc {
-		result = normalizeOrderByAsc(result)
+// steps returns the transformations enabled by the options, in the order they are applied.
+func (n *Normalizer) steps() []step {
+	var steps []step
+	add := func(enabled bool, name string, apply func([]token) []token) {
+		if enabled {
+			steps = append(steps, step{name: name, apply: apply})
+		}
 	}
 
-	if n.options.SortInsertColumns {
-		result = sortInsertColumns(result)
+	add(n.options.RemoveComments, "RemoveComments", removeComments)
+	add(n.options.RemoveQuotes, "RemoveQuotes", removeQuotes)
+	add(n.options.UnifyPlaceholders, "UnifyPlaceholders", unifyPlaceholders)
+	add(n.spec.foldLower, "FoldIdentifiers", func(tokens []token) []token {
+		return foldIdentifiers(tokens, n.spec.keywords)
+	})
+	add(n.options.UppercaseKeywords, "UppercaseKeywords", func(tokens []token) []token {
+		return uppercaseKeywords(tokens, n.spec.keywords)
+	})
+	add(n.options.SpaceOperators, "SpaceOperators", spaceOperators)
+	n.addRewrites(&steps, StageLexical)
+	add(n.options.ParameterizeLiterals, "ParameterizeLiterals", func(tokens []token) []token {
+		return parameterizeLiterals(tokens, n.spec)
+	})
+	add(n.options.CollapseInLists, "CollapseInLists", collapseInLists)
+	add(n.options.NormalizeSelectColumns, "NormalizeSelectColumns", normalizeSelectColumns)
+	add(n.options.NormalizeJoinSyntax, "NormalizeJoinSyntax", normalizeJoinSyntax)
+	add(n.options.NormalizeOrderByAsc, "NormalizeOrderByAsc", normalizeOrderByAsc)
+	add(n.options.NormalizePagination, "NormalizePagination", normalizePagination)
+	add(n.options.SortInsertColumns, "SortInsertColumns", sortInsertColumns)
+	add(n.options.SortUpdateColumns, "SortUpdateColumns", sortUpdateColumns)
+	add(n.options.NormalizeUpserts, "NormalizeUpserts", normalizeUpserts)
+	add(n.options.RemoveRedundantParens, "RemoveRedundantParens", removeRedundantParens)
+	add(n.options.NormalizeSoftDeletes, "NormalizeSoftDeletes", normalizeSoftDeletePredicates)
+	add(n.options.RemovePrimaryKeyLimit, "RemovePrimaryKeyLimit", removePrimaryKeyLimit)
+	add(n.options.SortPredicates, "SortPredicates", sortPredicates)
+	add(n.options.RemoveReturningClause, "RemoveReturningClause", removeReturningClause)
+	add(n.options.NormalizeTableQualifiers, "NormalizeTableQualifiers", normalizeTableQualifiers)
+	n.addRewrites(&steps, StageFinal)
+
+	return steps
+}
+
+// addRewrites appends the rewrite rules of a stage as steps named Rewrite[i],
+// where i is the index of the rule in Options.Rewrites.
+func (n *Normalizer) addRewrites(steps *[]step, stage Stage) {
+	for i, r := range n.options.Rewrites {
+		if r.Stage == stage {
+			*steps = append(*steps, step{name: fmt.Sprintf("Rewrite[%d]", i), apply: n.rewrite(r.Apply)})
+		}
 	}
+}
 
-	if n.options.SortUpdateColumns {
-		result = sortUpdateColumns(result)
+// rewrite returns a step that applies fn to the rendered tokens and tokenizes
+// the result again. The placeholders of the result take over the arguments of
+// the original placeholders in order, and tokens fn left unchanged keep their
+// kind, so that identifiers unquoted by RemoveQuotes are not read as keywords.
+func (n *Normalizer) rewrite(fn func(string) string) func([]token) []token {
+	return func(tokens []token) []token {
+		var placeholders []token
+		for _, t := range tokens {
+			if t.kind == tokenPlaceholder {
+				placeholders = append(placeholders, t)
+			}
+		}
+
+		rewritten := n.normalizedTokens(fn(render(withSpace(tokens, false))))
+		keepQuotedIdents(tokens, rewritten)
+		i := 0
+		for k := range rewritten {
+			if rewritten[k].kind != tokenPlaceholder {
+				continue
+			}
+			rewritten[k].arg = -1
+			if i < len(placeholders) {
+				p := placeholders[i]
+				p.text, p.space = rewritten[k].text, rewritten[k].space
+				rewritten[k] = p
+			}
+			i++
+		}
+		return rewritten
 	}
+}
 
-	if n.options.RemoveReturningClause {
-		result = removeReturningClause(result)
+// keepQuotedIdents marks the tokens of rewritten that are unchanged from a
+// quoted identifier of original as quoted identifiers again. Unchanged tokens
+// are found on the longest common subsequence of the token texts, after the
+// common prefix and suffix.
+func keepQuotedIdents(original, rewritten []token) {
+	keep := func(o, r int) {
+		if original[o].kind == tokenQuotedIdent && rewritten[r].kind == tokenIdent {
+			rewritten[r].kind = tokenQuotedIdent
+		}
 	}
 
-	if n.options.NormalizeTableQualifiers {
-		result = normalizeTableQualifiers(result)
+	prefix := 0
+	for prefix < len(original) && prefix < len(rewritten) && original[prefix].text == rewritten[prefix].text {
+		keep(prefix, prefix)
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(original)-prefix && suffix < len(rewritten)-prefix &&
+		original[len(original)-1-suffix].text == rewritten[len(rewritten)-1-suffix].text {
+		keep(len(original)-1-suffix, len(rewritten)-1-suffix)
+		suffix++
 	}
 
-	return strings.TrimSpace(result)
+	o, r := original[prefix:len(original)-suffix], rewritten[prefix:len(rewritten)-suffix]
+	lcs := make([][]int, len(o)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(r)+1)
+	}
+	for i := len(o) - 1; i >= 0; i-- {
+		for j := len(r) - 1; j >= 0; j-- {
+			if o[i].text == r[j].text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(o) && j < len(r); {
+		switch {
+		case o[i].text == r[j].text:
+			keep(prefix+i, prefix+j)
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
 }
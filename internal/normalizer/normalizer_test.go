@@ -1,6 +1,8 @@
 package normalizer
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -763,9 +765,209 @@ func TestNormalizer_Normalize(t *testing.T) {
 				NormalizeTableQualifiers: true,
 			},
 		},
+		{
+			name:     "sorts AND predicates",
+			input:    "SELECT * FROM users WHERE name = ? AND age > ? AND deleted_at IS NULL",
+			expected: "SELECT * FROM users WHERE age > ? AND deleted_at IS NULL AND name = ?",
+			options:  Options{UnifyPlaceholders: true, UppercaseKeywords: true, SortPredicates: true},
+		},
+		{
+			name:     "sorts OR predicates and nested groups",
+			input:    "SELECT * FROM users WHERE (role = ? OR admin = TRUE) AND status = ? OR id = ?",
+			expected: "SELECT * FROM users WHERE (admin = TRUE OR role = ?) AND status = ? OR id = ?",
+			options:  Options{UnifyPlaceholders: true, UppercaseKeywords: true, SortPredicates: true},
+		},
+		{
+			name:     "sorts predicates in subqueries and JOIN conditions",
+			input:    "SELECT * FROM users u JOIN orders o ON o.user_id = u.id AND o.paid WHERE u.id IN (SELECT user_id FROM tags WHERE tag = ? AND active) ORDER BY u.id",
+			expected: "SELECT * FROM users u JOIN orders o ON o.paid AND o.user_id = u.id WHERE u.id IN (SELECT user_id FROM tags WHERE active AND tag = ?) ORDER BY u.id",
+			options:  Options{UnifyPlaceholders: true, UppercaseKeywords: true, SortPredicates: true},
+		},
+		{
+			name:     "keeps BETWEEN and CASE operands together",
+			input:    "SELECT * FROM users WHERE CASE WHEN b AND a THEN 1 END = 1 AND age BETWEEN ? AND ?",
+			expected: "SELECT * FROM users WHERE CASE WHEN b AND a THEN 1 END = 1 AND age BETWEEN ? AND ?",
+			options:  Options{UnifyPlaceholders: true, UppercaseKeywords: true, SortPredicates: true},
+		},
+		{
+			name:     "preserves predicate order when disabled",
+			input:    "SELECT * FROM users WHERE name = ? AND age > ?",
+			expected: "SELECT * FROM users WHERE name = ? AND age > ?",
+			options:  DefaultOptions(),
+		},
+		// Literals are tokenized and never rewritten
+		{
+			name:     "preserves comment markers inside string literals",
+			input:    "SELECT * FROM users WHERE note = '-- not a comment' /* c */",
+			expected: "SELECT * FROM users WHERE note = '-- not a comment'",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "preserves placeholder-like text inside string literals",
+			input:    "SELECT * FROM users WHERE email = 'user@example.com' AND tag = ':tag' AND q = '?'",
+			expected: "SELECT * FROM users WHERE email = 'user@example.com' AND tag = ':tag' AND q = '?'",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "preserves keywords and whitespace inside string literals",
+			input:    "select * from users where name = 'select  from'",
+			expected: "SELECT * FROM users WHERE name = 'select  from'",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "preserves escaped quotes in string literals",
+			input:    "SELECT * FROM users WHERE name = 'O''Brien'",
+			expected: "SELECT * FROM users WHERE name = 'O''Brien'",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "preserves quote characters inside string literals",
+			input:    "SELECT * FROM users WHERE name = '\"quoted\" [x]'",
+			expected: "SELECT * FROM users WHERE name = '\"quoted\" [x]'",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "preserves dollar-quoted strings",
+			input:    "SELECT $tag$ it's -- $1 $tag$ FROM users WHERE id = $1",
+			expected: "SELECT $tag$ it's -- $1 $tag$ FROM users WHERE id = ?",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "keeps PostgreSQL type casts",
+			input:    "SELECT id::int FROM users WHERE id = $1::bigint",
+			expected: "SELECT id::int FROM users WHERE id = ?::bigint",
+			options:  DefaultOptions(),
+		},
+		{
+			name:     "removes redundant parentheses around predicates",
+			input:    "SELECT * FROM users WHERE ((id = ?)) AND (age > ? OR age IS NULL) AND id IN (SELECT id FROM t WHERE (x = 1))",
+			expected: "SELECT * FROM users WHERE id = ? AND (age > ? OR age IS NULL) AND id IN (SELECT id FROM t WHERE x = 1)",
+			options:  Options{RemoveRedundantParens: true},
+		},
+		{
+			name:     "removes parentheses around a whole condition",
+			input:    "SELECT * FROM users WHERE (a = ? OR b = ?) ORDER BY id",
+			expected: "SELECT * FROM users WHERE a = ? OR b = ? ORDER BY id",
+			options:  Options{RemoveRedundantParens: true},
+		},
+		{
+			name:     "moves soft-delete predicates to the end of the condition",
+			input:    "SELECT * FROM `users` WHERE `users`.`deleted_at` IS NULL AND `users`.`id` = ?",
+			expected: "SELECT * FROM users WHERE users.id = ? AND users.deleted_at IS NULL",
+			options:  Options{RemoveQuotes: true, UppercaseKeywords: true, NormalizeSoftDeletes: true},
+		},
+		{
+			name:     "unwraps and sorts soft-delete predicates in join conditions",
+			input:    `SELECT * FROM users u JOIN orders o ON (o.deleted_at is null) AND o.user_id = u.id WHERE ("u"."deleted_at" is null) ORDER BY id`,
+			expected: "SELECT * FROM users u JOIN orders o ON o.user_id = u.id AND o.deleted_at IS NULL WHERE u.deleted_at IS NULL ORDER BY id",
+			options:  Options{RemoveQuotes: true, UppercaseKeywords: true, NormalizeSoftDeletes: true},
+		},
+		{
+			name:     "keeps soft-delete predicate under OR",
+			input:    "SELECT * FROM users WHERE deleted_at IS NULL OR id = ?",
+			expected: "SELECT * FROM users WHERE deleted_at IS NULL OR id = ?",
+			options:  Options{NormalizeSoftDeletes: true},
+		},
+		{
+			name:     "sorts predicates on quoted keyword columns",
+			input:    `SELECT * FROM t WHERE "order" = ? AND a = ?`,
+			expected: "SELECT * FROM t WHERE a = ? AND order = ?",
+			options:  Options{RemoveQuotes: true, UppercaseKeywords: true, SortPredicates: true},
+		},
+		{
+			name:     "removes LIMIT 1 when WHERE fixes the id",
+			input:    "SELECT * FROM users WHERE id = ? AND deleted_at IS NULL LIMIT 1;",
+			expected: "SELECT * FROM users WHERE id = ? AND deleted_at IS NULL",
+			options:  Options{RemovePrimaryKeyLimit: true},
+		},
+		{
+			name:     "keeps LIMIT 1 without equality on the id",
+			input:    "SELECT * FROM users WHERE name = ? LIMIT 1",
+			expected: "SELECT * FROM users WHERE name = ? LIMIT 1",
+			options:  Options{RemovePrimaryKeyLimit: true},
+		},
+		{
+			name:     "spaces comparison operators",
+			input:    `select * from "users" where "id"=$1 and age>=? and name<>'x'`,
+			expected: "select * from users where id = ? and age >= ? and name <> 'x'",
+			options:  Options{RemoveQuotes: true, UnifyPlaceholders: true, SpaceOperators: true},
+		},
+		{
+			name:     "keeps ORDER BY LIMIT without equality on the column",
+			input:    "SELECT * FROM users WHERE name = ? ORDER BY users.id LIMIT 1",
+			expected: "SELECT * FROM users WHERE name = ? ORDER BY users.id LIMIT 1",
+			options:  Options{RemovePrimaryKeyLimit: true},
+		},
+		{
+			name:     "rewrites FETCH FIRST ROW ONLY to LIMIT 1",
+			input:    "SELECT * FROM users ORDER BY id FETCH FIRST ROW ONLY",
+			expected: "SELECT * FROM users ORDER BY id LIMIT 1",
+			options:  Options{NormalizePagination: true},
+		},
+		{
+			name:     "keeps TOP PERCENT and TOP in set operations",
+			input:    "SELECT TOP 10 PERCENT * FROM a UNION SELECT TOP 5 * FROM b",
+			expected: "SELECT TOP 10 PERCENT * FROM a UNION SELECT TOP 5 * FROM b",
+			options:  Options{NormalizePagination: true},
+		},
+		{
+			name:     "keeps canonical pagination",
+			input:    "SELECT * FROM users LIMIT 10 OFFSET 20",
+			expected: "SELECT * FROM users LIMIT 10 OFFSET 20",
+			options:  Options{NormalizePagination: true},
+		},
+		{
+			name:     "rewrites ON DUPLICATE KEY UPDATE",
+			input:    "INSERT INTO users (id, name, age) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name), age = age + VALUES(age)",
+			expected: "INSERT INTO users (id, name, age) VALUES (?, ?, ?) ON CONFLICT DO UPDATE SET age = age + EXCLUDED.age, name = EXCLUDED.name",
+			options:  Options{NormalizeUpserts: true},
+		},
+		{
+			name:     "rewrites MySQL row aliases",
+			input:    "INSERT INTO users (id, name) VALUES (?, ?) AS new ON DUPLICATE KEY UPDATE name = new.name;",
+			expected: "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT DO UPDATE SET name = EXCLUDED.name;",
+			options:  Options{NormalizeUpserts: true},
+		},
+		{
+			name:     "sorts ON CONFLICT target and assignments",
+			input:    "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (tenant_id, id) DO UPDATE SET name = excluded.name, id = excluded.id WHERE users.active RETURNING id",
+			expected: "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id, tenant_id) DO UPDATE SET id = EXCLUDED.id, name = EXCLUDED.name WHERE users.active RETURNING id",
+			options:  Options{NormalizeUpserts: true},
+		},
+		{
+			name:     "rewrites no-op updates and INSERT IGNORE to DO NOTHING",
+			input:    "INSERT IGNORE INTO users (id) VALUES (?)",
+			expected: "INSERT INTO users (id) VALUES (?) ON CONFLICT DO NOTHING",
+			options:  Options{NormalizeUpserts: true},
+		},
+		{
+			name:     "rewrites GORM DoNothing on MySQL",
+			input:    "INSERT INTO `users` (`id`) VALUES (?) ON DUPLICATE KEY UPDATE `id`=`id`",
+			expected: "INSERT INTO users (id) VALUES (?) ON CONFLICT DO NOTHING",
+			options:  Options{NormalizeUpserts: true, RemoveQuotes: true},
+		},
+		{
+			name:     "rewrites INSERT OR REPLACE",
+			input:    "INSERT OR REPLACE INTO users (name, id) VALUES (?, ?)",
+			expected: "INSERT INTO users (name, id) VALUES (?, ?) ON CONFLICT DO UPDATE SET id = EXCLUDED.id, name = EXCLUDED.name",
+			options:  Options{NormalizeUpserts: true},
+		},
+		{
+			name:     "keeps plain INSERT",
+			input:    "INSERT INTO users (id) SELECT id FROM a JOIN b ON a.id = b.id",
+			expected: "INSERT INTO users (id) SELECT id FROM a JOIN b ON a.id = b.id",
+			options:  Options{NormalizeUpserts: true},
+		},
+		{
+			name:     "preserves numeric literals",
+			input:    "SELECT * FROM users WHERE score > 1.5e3 AND flags = 0xFF LIMIT 10",
+			expected: "SELECT * FROM users WHERE score > 1.5e3 AND flags = 0xFF LIMIT 10",
+			options:  DefaultOptions(),
+		},
 	}
 
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -774,6 +976,427 @@ func TestNormalizer_Normalize(t *testing.T) {
 			if result != tt.expected {
 				t.Errorf("Normalize(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
+
+			// Rewrite rules that leave the query unchanged do not change the result
+			identity := func(q string) string { return q }
+			opts := tt.options
+			opts.Rewrites = append(opts.Rewrites[:len(opts.Rewrites):len(opts.Rewrites)],
+				Rewrite{Stage: StageLexical, Apply: identity},
+				Rewrite{Stage: StageFinal, Apply: identity},
+			)
+			if result := New(opts).Normalize(tt.input); result != tt.expected {
+				t.Errorf("Normalize(%q) with identity rewrites = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Dialect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		input    string
+		expected string
+	}{
+		{
+			name:     "generic accepts all quotes and placeholders",
+			dialect:  DialectGeneric,
+			input:    "SELECT `id`, \"Name\", [email] FROM users WHERE id = $1 AND name = :name AND email = @email",
+			expected: "SELECT id, Name, email FROM users WHERE id = ? AND name = ? AND email = ?",
+		},
+		{
+			name:     "MySQL double quotes are string literals",
+			dialect:  DialectMySQL,
+			input:    "SELECT `id` FROM users WHERE name = \"Alice\" AND id = ?",
+			expected: "SELECT id FROM users WHERE name = \"Alice\" AND id = ?",
+		},
+		{
+			name:     "MySQL backslash escapes in strings",
+			dialect:  DialectMySQL,
+			input:    "SELECT * FROM users WHERE name = 'O\\'Brien -- x' # comment",
+			expected: "SELECT * FROM users WHERE name = 'O\\'Brien -- x'",
+		},
+		{
+			name:     "MySQL user variables are not placeholders",
+			dialect:  DialectMySQL,
+			input:    "SELECT @rownum := @rownum + 1, @@session.sql_mode FROM users where id = ?",
+			expected: "SELECT @rownum := @rownum + 1, @@session.sql_mode FROM users WHERE id = ?",
+		},
+		{
+			name:     "MySQL keywords",
+			dialect:  DialectMySQL,
+			input:    "insert ignore into users (id) values (?) on duplicate key update id = id",
+			expected: "INSERT IGNORE INTO users (id) VALUES (?) ON DUPLICATE KEY UPDATE id = id",
+		},
+		{
+			name:     "PostgreSQL folds unquoted identifiers",
+			dialect:  DialectPostgreSQL,
+			input:    `SELECT * FROM Users WHERE UserName = $1 AND "CreatedAt" > $2`,
+			expected: "SELECT * FROM users WHERE username = ? AND CreatedAt > ?",
+		},
+		{
+			name:     "PostgreSQL question mark is an operator",
+			dialect:  DialectPostgreSQL,
+			input:    "SELECT * FROM users WHERE data ? 'key' AND id = $1",
+			expected: "SELECT * FROM users WHERE data ? 'key' AND id = ?",
+		},
+		{
+			name:     "PostgreSQL keywords",
+			dialect:  DialectPostgreSQL,
+			input:    "insert into users (id) values ($1) on conflict (id) do nothing",
+			expected: "INSERT INTO users (id) VALUES (?) ON CONFLICT (id) DO NOTHING",
+		},
+		{
+			name:     "PostgreSQL escape strings",
+			dialect:  DialectPostgreSQL,
+			input:    "SELECT * FROM users WHERE name = E'it\\'s' AND id = $1",
+			expected: "SELECT * FROM users WHERE name = E'it\\'s' AND id = ?",
+		},
+		{
+			name:     "SQLite placeholders",
+			dialect:  DialectSQLite,
+			input:    "SELECT * FROM Users WHERE id = ?1 AND name = $name AND email = :email",
+			expected: "SELECT * FROM users WHERE id = ? AND name = ? AND email = ?",
+		},
+		{
+			name:     "SQL Server brackets and placeholders",
+			dialect:  DialectSQLServer,
+			input:    "select top (@p1) [Id] from Users where Name = @name and @@ROWCOUNT > 0",
+			expected: "SELECT TOP (?) Id FROM users WHERE name = ? AND @@rowcount > 0",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := DefaultOptions()
+			opts.Dialect = tt.dialect
+			result := New(opts).Normalize(tt.input)
+			if result != tt.expected {
+				t.Errorf("Normalize(%q) with %v = %q, want %q", tt.input, tt.dialect, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_NormalizeArgs(t *testing.T) {
+	t.Parallel()
+
+	semantic := DefaultOptions()
+	semantic.SortInsertColumns = true
+	semantic.SortUpdateColumns = true
+
+	collapse := DefaultOptions()
+	collapse.CollapseInLists = true
+
+	parameterize := DefaultOptions()
+	parameterize.ParameterizeLiterals = true
+
+	paginate := DefaultOptions()
+	paginate.NormalizePagination = true
+
+	tests := []struct {
+		name         string
+		input        string
+		args         []any
+		expected     string
+		expectedArgs []any
+		options      Options
+	}{
+		{
+			name:         "keeps positional args in order",
+			input:        "SELECT * FROM users WHERE id = ? AND name = ?",
+			args:         []any{1, "Alice"},
+			expected:     "SELECT * FROM users WHERE id = ? AND name = ?",
+			expectedArgs: []any{1, "Alice"},
+			options:      DefaultOptions(),
+		},
+		{
+			name:         "maps numbered placeholders to args",
+			input:        "SELECT * FROM users WHERE name = $2 AND id = $1",
+			args:         []any{1, "Alice"},
+			expected:     "SELECT * FROM users WHERE name = ? AND id = ?",
+			expectedArgs: []any{"Alice", 1},
+			options:      DefaultOptions(),
+		},
+		{
+			name:         "reorders args with sorted INSERT columns",
+			input:        "INSERT INTO users (name, email, age) VALUES (?, ?, ?)",
+			args:         []any{"Alice", "alice@example.com", 30},
+			expected:     "INSERT INTO users (age, email, name) VALUES (?, ?, ?)",
+			expectedArgs: []any{30, "alice@example.com", "Alice"},
+			options:      semantic,
+		},
+		{
+			name:         "reorders args with sorted UPDATE columns",
+			input:        "UPDATE users SET name = ?, age = ? WHERE id = ?",
+			args:         []any{"Alice", 30, 1},
+			expected:     "UPDATE users SET age = ?, name = ? WHERE id = ?",
+			expectedArgs: []any{30, "Alice", 1},
+			options:      semantic,
+		},
+		{
+			name:         "reorders args with sorted predicates",
+			input:        "SELECT * FROM users WHERE name = ? AND (role = ? OR age > ?)",
+			args:         []any{"Alice", "admin", 18},
+			expected:     "SELECT * FROM users WHERE (age > ? OR role = ?) AND name = ?",
+			expectedArgs: []any{18, "admin", "Alice"},
+			options:      Options{UnifyPlaceholders: true, UppercaseKeywords: true, SortPredicates: true},
+		},
+		{
+			name:         "maps SQL Server ordinal placeholders to args",
+			input:        "SELECT * FROM users WHERE name = @p2 AND id = @p1",
+			args:         []any{1, "Alice"},
+			expected:     "SELECT * FROM users WHERE name = ? AND id = ?",
+			expectedArgs: []any{"Alice", 1},
+			options:      Options{UnifyPlaceholders: true, Dialect: DialectSQLServer},
+		},
+		{
+			name:         "ignores placeholders inside string literals",
+			input:        "SELECT * FROM users WHERE note = '?' AND id = ?",
+			args:         []any{1},
+			expected:     "SELECT * FROM users WHERE note = '?' AND id = ?",
+			expectedArgs: []any{1},
+			options:      DefaultOptions(),
+		},
+		{
+			name:         "removes primary key limit with its arg",
+			input:        "SELECT * FROM users WHERE users.id = ? ORDER BY users.id LIMIT ?",
+			args:         []any{7, 1},
+			expected:     "SELECT * FROM users WHERE users.id = ?",
+			expectedArgs: []any{7},
+			options:      Options{RemovePrimaryKeyLimit: true},
+		},
+		{
+			name:         "carries args through rewrite rules",
+			input:        "SELECT * FROM accounts WHERE id = $2 AND name = $1",
+			args:         []any{"Alice", 1},
+			expected:     "SELECT * FROM users WHERE id = ? AND name = ?",
+			expectedArgs: []any{1, "Alice"},
+			options: Options{UnifyPlaceholders: true, Rewrites: []Rewrite{{
+				Stage: StageLexical,
+				Apply: func(q string) string { return strings.Replace(q, "accounts", "users", 1) },
+			}}},
+		},
+		{
+			name:         "returns args unchanged when a rewrite rule adds placeholders",
+			input:        "SELECT * FROM users WHERE id = ?",
+			args:         []any{1},
+			expected:     "SELECT * FROM users WHERE id = ? AND tenant_id = ?",
+			expectedArgs: []any{1},
+			options: Options{UnifyPlaceholders: true, Rewrites: []Rewrite{{
+				Stage: StageFinal,
+				Apply: func(q string) string { return q + " AND tenant_id = ?" },
+			}}},
+		},
+		{
+			name:         "groups the args of collapsed IN lists",
+			input:        "SELECT * FROM orders WHERE user_id IN ($1, $2, $3) AND status = $4",
+			args:         []any{1, 2, 3, "paid"},
+			expected:     "SELECT * FROM orders WHERE user_id IN (?) AND status = ?",
+			expectedArgs: []any{[]any{1, 2, 3}, "paid"},
+			options:      collapse,
+		},
+		{
+			name:         "keeps IN lists with literals",
+			input:        "SELECT * FROM orders WHERE status IN ('paid', ?) AND id NOT IN (?)",
+			args:         []any{"sent", 7},
+			expected:     "SELECT * FROM orders WHERE status IN ('paid', ?) AND id NOT IN (?)",
+			expectedArgs: []any{"sent", []any{7}},
+			options:      collapse,
+		},
+		{
+			name:         "keeps collapsed IN lists through rewrite rules",
+			input:        "SELECT * FROM accounts WHERE id IN (?, ?)",
+			args:         []any{1, 2},
+			expected:     "SELECT * FROM users WHERE id IN (?)",
+			expectedArgs: []any{[]any{1, 2}},
+			options: Options{UnifyPlaceholders: true, CollapseInLists: true, Rewrites: []Rewrite{{
+				Stage: StageFinal,
+				Apply: func(q string) string { return strings.Replace(q, "accounts", "users", 1) },
+			}}},
+		},
+		{
+			name:         "lifts inline literals into args in position order",
+			input:        "SELECT * FROM users WHERE name = 'O''Brien' AND age > ? AND score >= -1.5 AND active = true LIMIT 10",
+			args:         []any{18},
+			expected:     "SELECT * FROM users WHERE name = ? AND age > ? AND score >= ? AND active = ? LIMIT ?",
+			expectedArgs: []any{"O'Brien", 18, -1.5, true, int64(10)},
+			options:      parameterize,
+		},
+		{
+			name:         "keeps literals that are not values",
+			input:        "SELECT a - 1 FROM t WHERE d > DATE '2024-01-01' AND f = x'ff' AND g IS NULL ORDER BY 1",
+			expected:     "SELECT a - ? FROM t WHERE d > DATE '2024-01-01' AND f = x'ff' AND g IS NULL ORDER BY 1",
+			expectedArgs: []any{int64(1)},
+			options:      parameterize,
+		},
+		{
+			name:         "groups lifted IN lists",
+			input:        "SELECT * FROM users WHERE id IN (1, 2, $1)",
+			args:         []any{3},
+			expected:     "SELECT * FROM users WHERE id IN (?)",
+			expectedArgs: []any{[]any{int64(1), int64(2), 3}},
+			options:      Options{UnifyPlaceholders: true, ParameterizeLiterals: true, CollapseInLists: true},
+		},
+		{
+			name:         "reorders args of MySQL LIMIT skip, count",
+			input:        "SELECT * FROM users ORDER BY id LIMIT ?, ?",
+			args:         []any{20, 10},
+			expected:     "SELECT * FROM users ORDER BY id LIMIT ? OFFSET ?",
+			expectedArgs: []any{10, 20},
+			options:      paginate,
+		},
+		{
+			name:         "reorders args of OFFSET before LIMIT",
+			input:        "SELECT * FROM users WHERE age > $1 OFFSET $2 LIMIT $3 FOR UPDATE",
+			args:         []any{18, 20, 10},
+			expected:     "SELECT * FROM users WHERE age > ? LIMIT ? OFFSET ? FOR UPDATE",
+			expectedArgs: []any{18, 10, 20},
+			options:      paginate,
+		},
+		{
+			name:         "rewrites OFFSET FETCH",
+			input:        "SELECT * FROM users ORDER BY id OFFSET @p1 ROWS FETCH NEXT @p2 ROWS ONLY",
+			args:         []any{20, 10},
+			expected:     "SELECT * FROM users ORDER BY id LIMIT ? OFFSET ?",
+			expectedArgs: []any{10, 20},
+			options:      paginate,
+		},
+		{
+			name:         "moves TOP to LIMIT in nested statements",
+			input:        "SELECT * FROM users WHERE id IN (SELECT TOP (?) user_id FROM orders ORDER BY total DESC) AND age > ?;",
+			args:         []any{5, 18},
+			expected:     "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders ORDER BY total DESC LIMIT ?) AND age > ?;",
+			expectedArgs: []any{5, 18},
+			options:      paginate,
+		},
+		{
+			name:         "reorders args of sorted upsert assignments",
+			input:        "INSERT INTO users (id) VALUES ($1) ON DUPLICATE KEY UPDATE name = $2, age = $3",
+			args:         []any{1, "Alice", 30},
+			expected:     "INSERT INTO users (id) VALUES (?) ON CONFLICT DO UPDATE SET age = ?, name = ?",
+			expectedArgs: []any{1, 30, "Alice"},
+			options:      Options{UnifyPlaceholders: true, NormalizeUpserts: true},
+		},
+		{
+			name:         "returns args unchanged when placeholders do not match",
+			input:        "SELECT * FROM users",
+			args:         []any{18},
+			expected:     "SELECT * FROM users",
+			expectedArgs: []any{18},
+			options:      DefaultOptions(),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			n := New(tt.options)
+			result, args := n.NormalizeArgs(tt.input, tt.args)
+			if result != tt.expected {
+				t.Errorf("NormalizeArgs(%q) query = %q, want %q", tt.input, result, tt.expected)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("NormalizeArgs(%q) args = %v, want %v", tt.input, args, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestNormalizer_PlaceholderColumns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "comparison operators",
+			input:    "SELECT * FROM users WHERE users.age >= ? AND name = ? AND email LIKE ?",
+			expected: []string{"age", "name", "email"},
+		},
+		{
+			name:     "IN list and BETWEEN",
+			input:    "SELECT * FROM users WHERE id IN (?, ?) AND age BETWEEN ? AND ?",
+			expected: []string{"id", "id", "age", "age"},
+		},
+		{
+			name:     "INSERT values",
+			input:    "INSERT INTO users (name, created_at) VALUES (?, ?), (?, NOW())",
+			expected: []string{"name", "created_at", "name"},
+		},
+		{
+			name:     "UPDATE SET and LIMIT",
+			input:    "UPDATE users SET name = ? WHERE id = ? LIMIT ?",
+			expected: []string{"name", "id", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := NewDefault().PlaceholderColumns(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("PlaceholderColumns(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Tables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "joins and aliases",
+			input:    "SELECT u.id FROM users AS u LEFT JOIN orders o ON o.user_id = u.id",
+			expected: []string{"users", "orders"},
+		},
+		{
+			name:     "table list and subquery",
+			input:    `SELECT * FROM users, "public"."accounts" a WHERE id IN (SELECT user_id FROM orders)`,
+			expected: []string{"users", "public.accounts", "orders"},
+		},
+		{
+			name:     "function FROM is not a table",
+			input:    "SELECT EXTRACT(YEAR FROM created_at) FROM users WHERE a IS DISTINCT FROM b",
+			expected: []string{"users"},
+		},
+		{
+			name:     "writes",
+			input:    "INSERT INTO users (id) VALUES (?) ON DUPLICATE KEY UPDATE id = VALUES(id)",
+			expected: []string{"users"},
+		},
+		{
+			name:     "UPDATE with subquery",
+			input:    "UPDATE users SET name = ? WHERE id IN (SELECT id FROM banned FOR UPDATE)",
+			expected: []string{"users", "banned"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := NewDefault().Tables(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Tables(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
 		})
 	}
 }
@@ -795,9 +1418,11 @@ func TestNormalizer_AllKeywords(t *testing.T) {
 		"like", "between", "exists", "case", "when", "then", "else", "end",
 		"count", "sum", "avg", "min", "max", "coalesce", "nullif",
 		"true", "false", "returning",
+		"begin", "commit", "rollback", "transaction",
 	}
 
 	for _, kw := range keywords {
+		kw := kw
 		t.Run(kw, func(t *testing.T) {
 			t.Parallel()
 
@@ -822,3 +1447,139 @@ func stringToUpper(s string) string {
 	}
 	return string(result)
 }
+
+func TestNormalizer_Parse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // Clauses as "NAME: item | item"
+	}{
+		{
+			name:  "SELECT clauses",
+			input: "SELECT id, name FROM users u LEFT JOIN orders o ON o.user_id = u.id WHERE u.age BETWEEN ? AND ? AND o.id IS NULL ORDER BY id DESC LIMIT ?",
+			expected: []string{
+				"SELECT: id | name",
+				"FROM: users u",
+				"LEFT JOIN orders o ON: o.user_id = u.id",
+				"WHERE: u.age BETWEEN ? AND ? | o.id IS NULL",
+				"ORDER BY: id DESC",
+				"LIMIT: ?",
+			},
+		},
+		{
+			name:  "INSERT keys values by column",
+			input: "INSERT INTO users (name, age) VALUES (?, ?), (?, ?) RETURNING id",
+			expected: []string{
+				"INSERT INTO: users",
+				"COLUMNS: name | age",
+				"VALUES row 1: name=? | age=?",
+				"VALUES row 2: name=? | age=?",
+				"RETURNING: id",
+			},
+		},
+		{
+			name:  "UPDATE keys assignments by column",
+			input: "UPDATE users SET name = ?, count = count + 1 WHERE id = ?",
+			expected: []string{
+				"UPDATE: users",
+				"SET: name=name = ? | count=count = count + 1",
+				"WHERE: id = ?",
+			},
+		},
+		{
+			name:  "DELETE",
+			input: "DELETE FROM users WHERE id = ?;",
+			expected: []string{
+				"DELETE FROM: users",
+				"WHERE: id = ?",
+			},
+		},
+		{
+			name:  "subqueries in the select list are kept as items",
+			input: "SELECT (SELECT COUNT(*) FROM orders o WHERE o.user_id = u.id) AS n, id FROM users u",
+			expected: []string{
+				"SELECT: (SELECT …) AS n | id",
+				"FROM: users u",
+			},
+		},
+		{
+			name:  "set operations",
+			input: "SELECT id FROM a UNION ALL SELECT id FROM b",
+			expected: []string{
+				"SELECT: id",
+				"FROM: a",
+				"UNION ALL: SELECT …",
+			},
+		},
+		{
+			name:     "unsupported statement",
+			input:    "BEGIN",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stmt := NewDefault().Parse(tt.input)
+
+			var result []string
+			if stmt != nil {
+				for _, c := range stmt.Clauses {
+					items := make([]string, len(c.Items))
+					for i, item := range c.Items {
+						items[i] = item.Template
+						if item.Key != "" {
+							items[i] = item.Key + "=" + item.Template
+						}
+					}
+					result = append(result, c.Name+": "+strings.Join(items, " | "))
+				}
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Parse(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Explain(t *testing.T) {
+	t.Parallel()
+
+	opts := Options{
+		RemoveQuotes:      true,
+		UppercaseKeywords: true,
+		Rewrites: []Rewrite{
+			{Stage: StageRaw, Apply: func(q string) string { return strings.TrimSuffix(q, ";") }},
+		},
+	}
+	n := New(opts)
+
+	input := "select * from `users`;"
+	trace := n.Explain(input)
+
+	want := []TraceStep{
+		{Name: "Rewrite[0]", Input: "select * from `users`;", Output: "select * from `users`"},
+		{Name: "RemoveQuotes", Input: "select * from `users`", Output: "select * from users"},
+		{Name: "UppercaseKeywords", Input: "select * from users", Output: "SELECT * FROM users"},
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("Explain(%q) = %+v, want %+v", input, trace, want)
+	}
+	if got := trace[len(trace)-1].Output; got != n.Normalize(input) {
+		t.Errorf("Explain output %q differs from Normalize %q", got, n.Normalize(input))
+	}
+
+	// Every query of the default pipeline ends with the normalized query
+	for _, q := range []string{"SELECT  a,b FROM t -- note", `INSERT INTO "t" (b, a) VALUES ($1, $2)`} {
+		n := NewDefault()
+		trace := n.Explain(q)
+		if got := trace[len(trace)-1].Output; got != n.Normalize(q) {
+			t.Errorf("Explain(%q) ends with %q, want %q", q, got, n.Normalize(q))
+		}
+	}
+}
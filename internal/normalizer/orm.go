@@ -0,0 +1,226 @@
+package normalizer
+
+import (
+	"sort"
+	"strings"
+)
+
+// normalizeSoftDeletePredicates moves the soft-delete predicates that ORMs add
+// to WHERE and JOIN ... ON conditions to the end of the condition, without
+// parentheses and sorted, in nested statements as well. The predicates are
+// kept, so a query that drops the filter still differs from one that has it.
+// WHERE users.deleted_at IS NULL AND id = ? → WHERE id = ? AND users.deleted_at IS NULL
+// WHERE (deleted_at IS NULL) ORDER BY id → WHERE deleted_at IS NULL ORDER BY id
+// Conditions with a top-level OR are left unchanged.
+func normalizeSoftDeletePredicates(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if !isConditionKeyword(tokens, i) || t.is("HAVING") {
+			result = append(result, t)
+			continue
+		}
+
+		end := clauseEnd(tokens, i+1, isConditionEnd)
+		condition := tokens[i+1 : end]
+		i = end - 1
+
+		result = append(result, t)
+		if disjuncts, _ := splitLogical(condition, "OR"); len(disjuncts) > 1 {
+			result = append(result, normalizeSoftDeletePredicates(condition)...)
+			continue
+		}
+
+		conjuncts, and := splitLogical(condition, "AND")
+		kept := make([][]token, 0, len(conjuncts))
+		var softDeletes [][]token
+		for _, c := range conjuncts {
+			if predicate, ok := softDeletePredicate(c); ok {
+				softDeletes = append(softDeletes, predicate)
+			} else {
+				kept = append(kept, normalizeSoftDeletePredicates(c))
+			}
+		}
+		sort.SliceStable(softDeletes, func(a, b int) bool {
+			return renderKey(softDeletes[a]) < renderKey(softDeletes[b])
+		})
+		if and.text == "" {
+			and = newToken(tokenIdent, "AND", true)
+		}
+		result = append(result, withSpace(joinOperands(append(kept, softDeletes...), and), true)...)
+	}
+	return result
+}
+
+// softDeletePredicate returns tokens without enclosing parentheses if they
+// are a deleted_at IS NULL predicate, with an optional table qualifier.
+func softDeletePredicate(tokens []token) ([]token, bool) {
+	for len(tokens) >= 2 && tokens[0].isPunct("(") && matchingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+
+	end := scanName(tokens, 0)
+	if end == 0 || !strings.EqualFold(tokens[end-1].name(), "deleted_at") {
+		return nil, false
+	}
+	rest := tokens[end:]
+	return tokens, len(rest) == 2 && rest[0].is("IS") && rest[1].is("NULL")
+}
+
+// removeRedundantParens unwraps parenthesized predicates of WHERE, HAVING and
+// JOIN ... ON conditions where the parentheses do not change precedence,
+// as generated by query builders such as sqlboiler.
+// WHERE (id = ?) AND (age > ? OR age IS NULL) → WHERE id = ? AND (age > ? OR age IS NULL)
+// WHERE (a = ? OR b = ?) → WHERE a = ? OR b = ?
+func removeRedundantParens(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if !isConditionKeyword(tokens, i) {
+			result = append(result, t)
+			continue
+		}
+
+		end := clauseEnd(tokens, i+1, isConditionEnd)
+		condition := unwrapParens(tokens[i+1 : end])
+		i = end - 1
+
+		result = append(result, t)
+		if disjuncts, _ := splitLogical(condition, "OR"); len(disjuncts) > 1 {
+			result = append(result, withSpace(removeRedundantParens(condition), true)...)
+			continue
+		}
+
+		conjuncts, and := splitLogical(condition, "AND")
+		for j, c := range conjuncts {
+			if inner := unwrapParens(c); !hasTopLevelOr(inner) {
+				c = inner
+			}
+			conjuncts[j] = removeRedundantParens(c)
+		}
+		result = append(result, withSpace(joinOperands(conjuncts, and), true)...)
+	}
+	return result
+}
+
+// unwrapParens removes parentheses enclosing all of tokens, unless they
+// enclose a nested statement.
+func unwrapParens(tokens []token) []token {
+	for len(tokens) >= 2 && tokens[0].isPunct("(") && matchingParen(tokens, 0) == len(tokens)-1 &&
+		!tokens[1].is("SELECT") && !tokens[1].is("WITH") {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	return tokens
+}
+
+// hasTopLevelOr reports whether tokens contain OR outside of parentheses.
+func hasTopLevelOr(tokens []token) bool {
+	disjuncts, _ := splitLogical(tokens, "OR")
+	return len(disjuncts) > 1
+}
+
+// joinOperands joins operands with the separator token.
+func joinOperands(operands [][]token, separator token) []token {
+	separator.space = true
+	var result []token
+	for i, operand := range operands {
+		if i > 0 {
+			result = append(result, separator)
+		}
+		result = append(result, withSpace(operand, true)...)
+	}
+	return result
+}
+
+// removePrimaryKeyLimit removes the trailing ORDER BY col LIMIT 1 that ORMs
+// such as GORM add when loading a single row, if the WHERE condition already
+// restricts the same column to a single value. The column is assumed to be
+// unique, e.g. a primary key. Without ORDER BY, a bare LIMIT 1 as added by
+// sqlboiler's One() is removed if the condition restricts the id column.
+// SELECT * FROM users WHERE id = ? ORDER BY users.id LIMIT ? → SELECT * FROM users WHERE id = ?
+// SELECT * FROM users WHERE id = ? LIMIT 1 → SELECT * FROM users WHERE id = ?
+func removePrimaryKeyLimit(tokens []token) []token {
+	if len(tokens) == 0 || !tokens[0].is("SELECT") {
+		return tokens
+	}
+
+	order, limit, where := -1, -1, -1
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth > 0:
+		case t.is("WHERE"):
+			where = i
+		case t.is("ORDER") && i+1 < len(tokens) && tokens[i+1].is("BY"):
+			order = i
+		case t.is("LIMIT"):
+			limit = i
+		}
+	}
+	if where < 0 || limit < where {
+		return tokens
+	}
+
+	// [ORDER BY col [ASC|DESC]] LIMIT 1|?
+	cut, column := limit, "id"
+	if order >= 0 {
+		if order < where {
+			return tokens
+		}
+		i := order + 2
+		end := scanName(tokens, i)
+		if end == i {
+			return tokens
+		}
+		column = tokens[end-1].name()
+		i = end
+		if i < len(tokens) && (tokens[i].is("ASC") || tokens[i].is("DESC")) {
+			i++
+		}
+		if i != limit {
+			return tokens
+		}
+		cut = order
+	}
+	if limit+1 >= len(tokens) {
+		return tokens
+	}
+	if count := tokens[limit+1]; count.kind != tokenPlaceholder && !(count.kind == tokenNumber && count.text == "1") {
+		return tokens
+	}
+	i := limit + 2
+	if i < len(tokens) && tokens[i].isPunct(";") {
+		i++
+	}
+	if i != len(tokens) {
+		return tokens
+	}
+
+	condition := tokens[where+1 : clauseEnd(tokens, where+1, isConditionEnd)]
+	if disjuncts, _ := splitLogical(condition, "OR"); len(disjuncts) > 1 {
+		return tokens
+	}
+	conjuncts, _ := splitLogical(condition, "AND")
+	for _, c := range conjuncts {
+		if isColumnEquality(c, column) {
+			result := make([]token, 0, cut)
+			return append(result, tokens[:cut]...)
+		}
+	}
+	return tokens
+}
+
+// isColumnEquality reports whether tokens are a [table.]column = value predicate.
+func isColumnEquality(tokens []token, column string) bool {
+	end := scanName(tokens, 0)
+	if end == 0 || !strings.EqualFold(tokens[end-1].name(), column) {
+		return false
+	}
+	rest := tokens[end:]
+	return len(rest) == 2 && rest[0].isOperator("=") &&
+		(rest[1].kind == tokenPlaceholder || rest[1].kind == tokenNumber || rest[1].kind == tokenString)
+}
@@ -0,0 +1,214 @@
+package normalizer
+
+// normalizePagination rewrites the pagination clauses of each statement,
+// including nested statements, to LIMIT count OFFSET skip. Bind arguments
+// follow their placeholders, so they are reordered along with the clauses.
+// LIMIT 20, 10 → LIMIT 10 OFFSET 20
+// OFFSET ? LIMIT ? → LIMIT ? OFFSET ?
+// OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY → LIMIT 10 OFFSET 20
+// SELECT TOP 10 * FROM users ORDER BY id → SELECT * FROM users ORDER BY id LIMIT 10
+// Statements using PERCENT or WITH TIES, and TOP in statements combined with
+// UNION, INTERSECT or EXCEPT, are left unchanged.
+func normalizePagination(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].isPunct("(") {
+			if closeIdx := matchingParen(tokens, i); closeIdx > i {
+				result = append(result, tokens[i])
+				result = append(result, normalizePagination(tokens[i+1:closeIdx])...)
+				result = append(result, tokens[closeIdx])
+				i = closeIdx
+				continue
+			}
+		}
+		result = append(result, tokens[i])
+	}
+	return paginate(result)
+}
+
+// paginate rewrites the pagination clauses outside of parentheses.
+// It returns tokens unchanged if the clauses cannot be rewritten.
+func paginate(tokens []token) []token {
+	var count, skip []token
+	removed := make([]bool, len(tokens))
+	insert := -1 // Index of the first LIMIT, OFFSET or FETCH clause
+	top, setOperation := false, false
+
+	remove := func(from, to int) {
+		for k := from; k < to; k++ {
+			removed[k] = true
+		}
+	}
+	set := func(target *[]token, operand []token) bool {
+		if *target != nil || operand == nil {
+			return false
+		}
+		*target = operand
+		return true
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isPunct("("):
+			if closeIdx := matchingParen(tokens, i); closeIdx > i {
+				i = closeIdx
+			}
+			continue
+		case t.is("UNION"), t.is("INTERSECT"), t.is("EXCEPT"):
+			setOperation = true
+			continue
+		}
+
+		var end int
+		switch {
+		case t.is("LIMIT"):
+			// MySQL: LIMIT skip, count
+			operand, next := paginationOperand(tokens, i+1)
+			if next < len(tokens) && tokens[next].isPunct(",") {
+				var second []token
+				second, next = paginationOperand(tokens, next+1)
+				if !set(&skip, operand) || !set(&count, second) {
+					return tokens
+				}
+			} else if !set(&count, operand) {
+				return tokens
+			}
+			end = next
+		case t.is("OFFSET"):
+			operand, next := paginationOperand(tokens, i+1)
+			if !set(&skip, operand) {
+				return tokens
+			}
+			if next < len(tokens) && (tokens[next].is("ROW") || tokens[next].is("ROWS")) {
+				next++
+			}
+			end = next
+		case t.is("FETCH"):
+			// FETCH FIRST|NEXT [count] ROW|ROWS ONLY
+			next := i + 1
+			if next >= len(tokens) || !(tokens[next].is("FIRST") || tokens[next].is("NEXT")) {
+				return tokens
+			}
+			next++
+			operand := []token{newToken(tokenNumber, "1", true)}
+			if next < len(tokens) && !tokens[next].is("ROW") && !tokens[next].is("ROWS") {
+				operand, next = paginationOperand(tokens, next)
+			}
+			if next+1 >= len(tokens) || !(tokens[next].is("ROW") || tokens[next].is("ROWS")) || !tokens[next+1].is("ONLY") {
+				return tokens
+			}
+			if !set(&count, operand) {
+				return tokens
+			}
+			end = next + 2
+		case t.is("TOP") && isTopPosition(tokens, i):
+			operand, next := paginationOperand(tokens, i+1)
+			if len(operand) == 3 {
+				// TOP (10) → 10
+				operand = operand[1:2]
+			}
+			if next < len(tokens) && (tokens[next].is("PERCENT") || tokens[next].is("WITH")) {
+				return tokens
+			}
+			if !set(&count, operand) {
+				return tokens
+			}
+			remove(i, next)
+			top = true
+			i = next - 1
+			continue
+		default:
+			continue
+		}
+
+		remove(i, end)
+		if insert < 0 {
+			insert = i
+		}
+		i = end - 1
+	}
+
+	if count == nil && skip == nil {
+		return tokens
+	}
+	if top && setOperation {
+		return tokens
+	}
+	if insert < 0 {
+		insert = paginationEnd(tokens)
+	}
+
+	var clauses []token
+	if count != nil {
+		clauses = append(clauses, newToken(tokenIdent, "LIMIT", true))
+		clauses = append(clauses, withSpace(count, true)...)
+	}
+	if skip != nil {
+		clauses = append(clauses, newToken(tokenIdent, "OFFSET", true))
+		clauses = append(clauses, withSpace(skip, true)...)
+	}
+
+	result := make([]token, 0, len(tokens)+len(clauses))
+	for k, t := range tokens {
+		if k == insert {
+			result = append(result, clauses...)
+		}
+		if !removed[k] {
+			result = append(result, t)
+		}
+	}
+	if insert == len(tokens) {
+		result = append(result, clauses...)
+	}
+	return result
+}
+
+// paginationOperand returns the row count at i, a placeholder, a number, ALL
+// or a parenthesized expression, and the index after it. It returns nil if
+// there is no row count at i.
+func paginationOperand(tokens []token, i int) ([]token, int) {
+	if i >= len(tokens) {
+		return nil, i
+	}
+	t := tokens[i]
+	switch {
+	case t.kind == tokenPlaceholder, t.kind == tokenNumber, t.is("ALL"):
+		return tokens[i : i+1], i + 1
+	case t.isPunct("("):
+		if closeIdx := matchingParen(tokens, i); closeIdx > i {
+			return tokens[i : closeIdx+1], closeIdx + 1
+		}
+	}
+	return nil, i
+}
+
+// isTopPosition reports whether the TOP at i follows SELECT [DISTINCT|ALL].
+func isTopPosition(tokens []token, i int) bool {
+	j := i - 1
+	if j >= 0 && (tokens[j].is("DISTINCT") || tokens[j].is("ALL")) {
+		j--
+	}
+	return j >= 0 && tokens[j].is("SELECT")
+}
+
+// paginationEnd returns the index where a LIMIT clause is added to a statement
+// without one: before a locking clause (FOR UPDATE, ...) or a trailing
+// semicolon, or at the end.
+func paginationEnd(tokens []token) int {
+	end := len(tokens)
+	for end > 0 && tokens[end-1].isPunct(";") {
+		end--
+	}
+	for i := 0; i < end; i++ {
+		switch {
+		case tokens[i].isPunct("("):
+			if closeIdx := matchingParen(tokens, i); closeIdx > i {
+				i = closeIdx
+			}
+		case tokens[i].is("FOR"):
+			return i
+		}
+	}
+	return end
+}
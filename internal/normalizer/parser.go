@@ -0,0 +1,436 @@
+package normalizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ucpr/migratiorm/internal/ast"
+)
+
+// Parse parses a normalized query into a statement tree for structural comparison.
+// Bind argument indexes in the tree refer to the placeholders of the query in order,
+// i.e. to the arguments returned by NormalizeArgs.
+// It returns nil for statements other than SELECT, INSERT, UPDATE and DELETE,
+// and for queries it cannot parse.
+func (n *Normalizer) Parse(query string) *ast.Statement {
+	tokens := n.normalizedTokens(query)
+
+	position := 0
+	for i := range tokens {
+		if tokens[i].kind == tokenPlaceholder {
+			tokens[i].arg = position
+			position++
+		}
+	}
+
+	for len(tokens) > 0 && tokens[len(tokens)-1].isPunct(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return parseStatement(tokens)
+}
+
+// normalizedTokens tokenizes a normalized query with the normalizer's dialect.
+// Unified ? placeholders are recognized in every dialect.
+func (n *Normalizer) normalizedTokens(query string) []token {
+	spec := n.spec
+	if n.options.UnifyPlaceholders {
+		spec.placeholders |= placeholderQuestion
+	}
+	return tokenize(query, spec)
+}
+
+// parser holds the state of parsing a single statement.
+type parser struct {
+	tokens    []token
+	stmt      *ast.Statement
+	columns   []string // INSERT column names, used as keys of VALUES items
+	sawValues bool
+}
+
+// parseStatement parses tokens into a statement, or returns nil.
+func parseStatement(tokens []token) *ast.Statement {
+	p := &parser{tokens: tokens, stmt: &ast.Statement{}}
+	if !p.parse() || p.stmt.Type == "" {
+		return nil
+	}
+	return p.stmt
+}
+
+// parse splits the statement into clauses.
+func (p *parser) parse() bool {
+	for i := 0; i < len(p.tokens); {
+		kw, ok := p.keywordAt(i)
+		if !ok {
+			return false
+		}
+		if p.stmt.Type == "" && kw.statement != "" {
+			p.stmt.Type = kw.statement
+		}
+
+		var next int
+		switch kw.kind {
+		case keywordJoin:
+			next = p.join(i, kw)
+		case keywordInsert:
+			next = p.insertInto(i, kw)
+		case keywordValues:
+			next = p.values(i + kw.width)
+		case keywordSetOperation:
+			rest := parseStatement(p.tokens[i+kw.width:])
+			if rest == nil {
+				return false
+			}
+			p.addClause(kw.name, ast.ClauseSetOperation, []*ast.Expr{{
+				SQL:        renderKey(p.tokens[i+kw.width:]),
+				Template:   "SELECT …",
+				Subqueries: []*ast.Statement{rest},
+			}})
+			next = len(p.tokens)
+		default:
+			next = p.clauseEnd(i + kw.width)
+			p.addClause(kw.name, kw.clause, p.items(kw.clause, p.tokens[i+kw.width:next]))
+		}
+
+		if next <= i {
+			return false
+		}
+		i = next
+	}
+	return true
+}
+
+// keywordKind identifies keywords that need special parsing.
+type keywordKind int
+
+const (
+	keywordClause       keywordKind = iota // Keywords followed by the clause items
+	keywordJoin                            // JOIN table [ON predicates | USING (columns)]
+	keywordInsert                          // INSERT [...] INTO table [(columns)]
+	keywordValues                          // VALUES (row), (row)
+	keywordSetOperation                    // UNION [ALL] query
+)
+
+// keyword is a clause keyword found by keywordAt.
+type keyword struct {
+	name      string // Clause name (the keywords separated by single spaces)
+	width     int    // Number of keyword tokens
+	kind      keywordKind
+	clause    ast.ClauseKind
+	statement string // Statement type started by the keyword, if any
+}
+
+// keywordAt returns the clause keyword starting at i, if any.
+func (p *parser) keywordAt(i int) (keyword, bool) {
+	t := p.tokens[i]
+	if t.kind != tokenIdent {
+		return keyword{}, false
+	}
+
+	start := len(p.stmt.Clauses) == 0 ||
+		(len(p.stmt.Clauses) == 1 && strings.HasPrefix(p.stmt.Clauses[0].Name, "WITH"))
+
+	switch {
+	case t.is("WITH") && len(p.stmt.Clauses) == 0:
+		return p.sequence(i, ast.ClauseList, "WITH", "RECURSIVE"), true
+	case t.is("SELECT") && (start || p.stmt.Type == "INSERT"):
+		kw := p.sequence(i, ast.ClauseSelect, "SELECT", "DISTINCT")
+		if kw.width == 1 {
+			kw = p.sequence(i, ast.ClauseSelect, "SELECT", "ALL")
+		}
+		if p.stmt.Type == "" {
+			kw.statement = "SELECT"
+		}
+		return kw, true
+	case t.is("INSERT") && start:
+		return keyword{name: "INSERT", kind: keywordInsert, statement: "INSERT"}, true
+	case t.is("UPDATE") && start:
+		return keyword{name: "UPDATE", width: 1, clause: ast.ClauseTables, statement: "UPDATE"}, true
+	case t.is("DELETE") && start:
+		// DELETE [targets] FROM table
+		for j := i + 1; j < len(p.tokens) && j <= i+3; j++ {
+			if p.tokens[j].is("FROM") {
+				return keyword{name: render(p.tokens[i : j+1]), width: j - i + 1, clause: ast.ClauseTables, statement: "DELETE"}, true
+			}
+		}
+		return keyword{}, false
+	case t.is("FROM"):
+		// IS [NOT] DISTINCT FROM is an operator
+		if i > 0 && p.tokens[i-1].is("DISTINCT") {
+			return keyword{}, false
+		}
+		return keyword{name: "FROM", width: 1, clause: ast.ClauseTables}, true
+	case t.is("WHERE"), t.is("HAVING"):
+		return keyword{name: strings.ToUpper(t.text), width: 1, clause: ast.ClausePredicates}, true
+	case t.is("GROUP"), t.is("ORDER"):
+		if !p.is(i+1, "BY") {
+			return keyword{}, false
+		}
+		return keyword{name: strings.ToUpper(t.text) + " BY", width: 2, clause: ast.ClauseList}, true
+	case t.is("WINDOW"):
+		return keyword{name: "WINDOW", width: 1, clause: ast.ClauseList}, true
+	case t.is("LIMIT"), t.is("OFFSET"), t.is("FETCH"), t.is("FOR"):
+		return keyword{name: strings.ToUpper(t.text), width: 1, clause: ast.ClauseExpr}, true
+	case t.is("RETURNING"):
+		return keyword{name: "RETURNING", width: 1, clause: ast.ClauseReturning}, true
+	case t.is("SET") && p.stmt.Type == "UPDATE":
+		return keyword{name: "SET", width: 1, clause: ast.ClauseAssignments}, true
+	case t.is("USING") && p.stmt.Type == "DELETE":
+		return keyword{name: "USING", width: 1, clause: ast.ClauseTables}, true
+	case (t.is("VALUES") || t.is("VALUE")) && p.stmt.Type == "INSERT" && !p.sawValues:
+		return keyword{name: "VALUES", width: 1, kind: keywordValues}, true
+	case t.is("ON") && p.is(i+1, "CONFLICT"):
+		return keyword{name: "ON CONFLICT", width: 2, clause: ast.ClauseExpr}, true
+	case t.is("ON") && p.is(i+1, "DUPLICATE") && p.is(i+2, "KEY") && p.is(i+3, "UPDATE"):
+		return keyword{name: "ON DUPLICATE KEY UPDATE", width: 4, clause: ast.ClauseAssignments}, true
+	case t.is("DO") && p.is(i+1, "NOTHING"):
+		return keyword{name: "DO NOTHING", width: 2, clause: ast.ClauseExpr}, true
+	case t.is("DO") && p.is(i+1, "UPDATE") && p.is(i+2, "SET"):
+		return keyword{name: "DO UPDATE SET", width: 3, clause: ast.ClauseAssignments}, true
+	case t.is("UNION"), t.is("INTERSECT"), t.is("EXCEPT"):
+		kw := p.sequence(i, ast.ClauseSetOperation, strings.ToUpper(t.text), "ALL")
+		if kw.width == 1 {
+			kw = p.sequence(i, ast.ClauseSetOperation, strings.ToUpper(t.text), "DISTINCT")
+		}
+		kw.kind = keywordSetOperation
+		return kw, true
+	}
+
+	if width := p.joinWidth(i); width > 0 {
+		return keyword{name: render(withSpace(p.tokens[i:i+width], false)), width: width, kind: keywordJoin}, true
+	}
+	return keyword{}, false
+}
+
+// sequence returns the keyword first, followed by the optional keyword second if present.
+func (p *parser) sequence(i int, clause ast.ClauseKind, first, second string) keyword {
+	if p.is(i+1, second) {
+		return keyword{name: first + " " + second, width: 2, clause: clause}
+	}
+	return keyword{name: first, width: 1, clause: clause}
+}
+
+// is reports whether the token at i is the keyword kw.
+func (p *parser) is(i int, kw string) bool {
+	return i < len(p.tokens) && p.tokens[i].is(kw)
+}
+
+// joinWidth returns the number of tokens of the join keywords at i, or 0:
+// [NATURAL] [INNER | CROSS | LEFT | RIGHT | FULL] [OUTER] JOIN, STRAIGHT_JOIN.
+func (p *parser) joinWidth(i int) int {
+	j := i
+	if p.is(j, "STRAIGHT_JOIN") {
+		return 1
+	}
+	if p.is(j, "NATURAL") {
+		j++
+	}
+	for _, kw := range []string{"INNER", "CROSS", "LEFT", "RIGHT", "FULL"} {
+		if p.is(j, kw) {
+			j++
+			break
+		}
+	}
+	if p.is(j, "OUTER") {
+		j++
+	}
+	if p.is(j, "JOIN") {
+		return j - i + 1
+	}
+	return 0
+}
+
+// clauseEnd returns the index of the next clause keyword at the same depth as start.
+func (p *parser) clauseEnd(start int) int {
+	depth := 0
+	for i := start; i < len(p.tokens); i++ {
+		switch {
+		case p.tokens[i].isPunct("("):
+			depth++
+		case p.tokens[i].isPunct(")"):
+			depth--
+		case depth == 0:
+			if _, ok := p.keywordAt(i); ok {
+				return i
+			}
+		}
+	}
+	return len(p.tokens)
+}
+
+// join parses JOIN table [ON predicates | USING (columns)].
+// The clause is named after the join and its table, so that joins are paired by table.
+func (p *parser) join(i int, kw keyword) int {
+	end := p.clauseEnd(i + kw.width)
+	body := p.tokens[i:end]
+
+	for j := kw.width; j < len(body); j++ {
+		if body[j].is("ON") {
+			p.addClause(render(withSpace(body[:j+1], false)), ast.ClausePredicates, p.items(ast.ClausePredicates, body[j+1:]))
+			return end
+		}
+	}
+	p.addClause(render(withSpace(body, false)), ast.ClausePredicates, nil)
+	return end
+}
+
+// insertInto parses INSERT [OR REPLACE | IGNORE ...] INTO table [(columns)].
+func (p *parser) insertInto(i int, kw keyword) int {
+	into := -1
+	for j := i + 1; j < len(p.tokens) && j <= i+3; j++ {
+		if p.tokens[j].is("INTO") {
+			into = j
+			break
+		}
+	}
+	if into < 0 {
+		return i
+	}
+
+	// The table ends at the column list or the next clause
+	end := into + 1
+	for end < len(p.tokens) && !p.tokens[end].isPunct("(") {
+		if _, ok := p.keywordAt(end); ok && end > into+1 {
+			break
+		}
+		end++
+	}
+	p.addClause(render(withSpace(p.tokens[i:into+1], false)), ast.ClauseTables, p.items(ast.ClauseTables, p.tokens[into+1:end]))
+
+	// INSERT INTO t (SELECT ...) has no column list
+	if end < len(p.tokens) && p.tokens[end].isPunct("(") && !p.is(end+1, "SELECT") && !p.is(end+1, "WITH") {
+		closeIdx := matchingParen(p.tokens, end)
+		if closeIdx < 0 {
+			return i
+		}
+		columns := p.items(ast.ClauseColumns, p.tokens[end+1:closeIdx])
+		for _, c := range columns {
+			p.columns = append(p.columns, c.SQL)
+		}
+		p.addClause("COLUMNS", ast.ClauseColumns, columns)
+		end = closeIdx + 1
+	}
+	return end
+}
+
+// values parses the rows of a VALUES clause starting at start.
+// Each row becomes a VALUES clause whose items are keyed by column.
+func (p *parser) values(start int) int {
+	p.sawValues = true
+
+	var rows [][]*ast.Expr
+	i := start
+	for i < len(p.tokens) && p.tokens[i].isPunct("(") {
+		closeIdx := matchingParen(p.tokens, i)
+		if closeIdx < 0 {
+			return start
+		}
+		row := p.items(ast.ClauseValues, p.tokens[i+1:closeIdx])
+		if len(row) == len(p.columns) {
+			for k, item := range row {
+				item.Key = p.columns[k]
+			}
+		}
+		rows = append(rows, row)
+		i = closeIdx + 1
+		if i < len(p.tokens) && p.tokens[i].isPunct(",") {
+			i++
+		}
+	}
+
+	for k, row := range rows {
+		name := "VALUES"
+		if len(rows) > 1 {
+			name = fmt.Sprintf("VALUES row %d", k+1)
+		}
+		p.addClause(name, ast.ClauseValues, row)
+	}
+	return i
+}
+
+func (p *parser) addClause(name string, kind ast.ClauseKind, items []*ast.Expr) {
+	p.stmt.Clauses = append(p.stmt.Clauses, &ast.Clause{Name: name, Kind: kind, Items: items})
+}
+
+// items splits the tokens of a clause into its items.
+func (p *parser) items(kind ast.ClauseKind, tokens []token) []*ast.Expr {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var parts [][]token
+	switch kind {
+	case ast.ClauseExpr:
+		parts = [][]token{tokens}
+	case ast.ClausePredicates:
+		parts = splitPredicates(tokens)
+	default:
+		parts = splitTopLevel(tokens)
+	}
+
+	items := make([]*ast.Expr, 0, len(parts))
+	for _, part := range parts {
+		item := newExpr(part)
+		if kind == ast.ClauseAssignments {
+			for k, t := range part {
+				if t.isOperator("=") {
+					item.Key = renderKey(part[:k])
+					break
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// splitPredicates splits tokens at top-level AND keywords, keeping
+// BETWEEN x AND y together.
+func splitPredicates(tokens []token) [][]token {
+	var parts [][]token
+	depth, start := 0, 0
+	between := false
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth > 0:
+		case t.is("BETWEEN"):
+			between = true
+		case t.is("AND") && between:
+			between = false
+		case t.is("AND"):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// newExpr creates an expression, parsing parenthesized statements as subqueries.
+func newExpr(tokens []token) *ast.Expr {
+	expr := &ast.Expr{SQL: renderKey(tokens)}
+
+	template := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.isPunct("(") && i+1 < len(tokens) && (tokens[i+1].is("SELECT") || tokens[i+1].is("WITH")) {
+			if closeIdx := matchingParen(tokens, i); closeIdx > 0 {
+				if sub := parseStatement(tokens[i+1 : closeIdx]); sub != nil {
+					expr.Subqueries = append(expr.Subqueries, sub)
+					template = append(template, t, newToken(tokenIdent, "SELECT …", false), tokens[closeIdx])
+					i = closeIdx
+					continue
+				}
+			}
+		}
+		if t.kind == tokenPlaceholder {
+			expr.Args = append(expr.Args, t.arg)
+		}
+		template = append(template, t)
+	}
+
+	expr.Template = renderKey(template)
+	return expr
+}
@@ -0,0 +1,148 @@
+package normalizer
+
+import (
+	"sort"
+)
+
+// sortPredicates sorts AND-ed and OR-ed predicates in WHERE, HAVING and JOIN ... ON
+// conditions, and in parenthesized conditions at every nesting level.
+// WHERE b = ? AND a = ? → WHERE a = ? AND b = ?
+// WHERE (y OR x) AND c → WHERE (x OR y) AND c
+// Placeholders move with their predicates, so their arguments stay aligned.
+func sortPredicates(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		switch {
+		case isConditionKeyword(tokens, i):
+			end := clauseEnd(tokens, i+1, isConditionEnd)
+			result = append(result, t)
+			result = append(result, sortCondition(tokens[i+1:end])...)
+			i = end - 1
+		case t.isPunct("("):
+			closeIdx := matchingParen(tokens, i)
+			if closeIdx < 0 {
+				result = append(result, tokens[i:]...)
+				return result
+			}
+			inner := tokens[i+1 : closeIdx]
+			if len(inner) > 0 && !inner[0].is("SELECT") && !inner[0].is("WITH") && hasLogicalOperator(inner) {
+				inner = sortCondition(inner)
+			} else {
+				inner = sortPredicates(inner)
+			}
+			result = append(result, t)
+			result = append(result, inner...)
+			result = append(result, tokens[closeIdx])
+			i = closeIdx
+		default:
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// isConditionKeyword reports whether the token at i starts a condition:
+// WHERE, HAVING or the ON of a join (not ON CONFLICT or ON DUPLICATE KEY).
+func isConditionKeyword(tokens []token, i int) bool {
+	t := tokens[i]
+	if t.is("WHERE") || t.is("HAVING") {
+		return true
+	}
+	if !t.is("ON") || i+1 >= len(tokens) {
+		return false
+	}
+	next := tokens[i+1]
+	return !next.is("CONFLICT") && !next.is("DUPLICATE")
+}
+
+// isConditionEnd reports whether the token ends a condition.
+func isConditionEnd(t token) bool {
+	if isClauseKeyword(t) {
+		return true
+	}
+	for _, kw := range []string{
+		"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "NATURAL", "STRAIGHT_JOIN",
+		"DO", "SET",
+	} {
+		if t.is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortCondition sorts the operands of a boolean expression.
+// OR has lower precedence than AND, so the expression is split into OR-ed
+// disjuncts first, and each disjunct into AND-ed conjuncts.
+func sortCondition(tokens []token) []token {
+	if len(tokens) == 0 {
+		return tokens
+	}
+	space := tokens[0].space
+
+	disjuncts, or := splitLogical(tokens, "OR")
+	for i, d := range disjuncts {
+		conjuncts, and := splitLogical(d, "AND")
+		for j, c := range conjuncts {
+			conjuncts[j] = sortPredicates(c)
+		}
+		disjuncts[i] = joinSorted(conjuncts, and)
+	}
+
+	return withSpace(joinSorted(disjuncts, or), space)
+}
+
+// splitLogical splits tokens at the logical operator op outside of parentheses
+// and CASE expressions. The AND of BETWEEN x AND y is not a logical operator.
+// It returns the operands and the first separator token.
+func splitLogical(tokens []token, op string) ([][]token, token) {
+	var parts [][]token
+	var separator token
+	depth, start := 0, 0
+	between := false
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("(") || t.is("CASE"):
+			depth++
+		case t.isPunct(")") || t.is("END"):
+			depth--
+		case depth > 0:
+		case t.is("BETWEEN"):
+			between = true
+		case t.is("AND") && between:
+			between = false
+		case t.is(op):
+			if len(parts) == 0 {
+				separator = t
+			}
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:]), separator
+}
+
+// hasLogicalOperator reports whether tokens contain AND or OR outside of
+// parentheses and CASE expressions.
+func hasLogicalOperator(tokens []token) bool {
+	if parts, _ := splitLogical(tokens, "OR"); len(parts) > 1 {
+		return true
+	}
+	parts, _ := splitLogical(tokens, "AND")
+	return len(parts) > 1
+}
+
+// joinSorted sorts operands and joins them with the separator.
+func joinSorted(operands [][]token, separator token) []token {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+
+	sort.SliceStable(operands, func(a, b int) bool {
+		return renderKey(operands[a]) < renderKey(operands[b])
+	})
+
+	return joinOperands(operands, separator)
+}
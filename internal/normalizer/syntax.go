@@ -1,7 +1,6 @@
 package normalizer
 
 import (
-	"regexp"
 	"strings"
 )
 
@@ -10,49 +9,48 @@ import (
 // - LEFT OUTER JOIN → LEFT JOIN
 // - RIGHT OUTER JOIN → RIGHT JOIN
 // - FULL OUTER JOIN → FULL JOIN
-func normalizeJoinSyntax(query string) string {
-	result := query
-
-	// INNER JOIN → JOIN (INNER is redundant)
-	re := regexp.MustCompile(`(?i)\bINNER\s+JOIN\b`)
-	result = re.ReplaceAllString(result, "JOIN")
-
-	// LEFT OUTER JOIN → LEFT JOIN (OUTER is redundant)
-	re = regexp.MustCompile(`(?i)\bLEFT\s+OUTER\s+JOIN\b`)
-	result = re.ReplaceAllString(result, "LEFT JOIN")
-
-	// RIGHT OUTER JOIN → RIGHT JOIN (OUTER is redundant)
-	re = regexp.MustCompile(`(?i)\bRIGHT\s+OUTER\s+JOIN\b`)
-	result = re.ReplaceAllString(result, "RIGHT JOIN")
+func normalizeJoinSyntax(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		// INNER JOIN → JOIN (INNER is redundant)
+		if t.is("INNER") && i+1 < len(tokens) && tokens[i+1].is("JOIN") {
+			result = append(result, newToken(tokenIdent, "JOIN", t.space))
+			i++
+			continue
+		}
 
-	// FULL OUTER JOIN → FULL JOIN (OUTER is redundant)
-	re = regexp.MustCompile(`(?i)\bFULL\s+OUTER\s+JOIN\b`)
-	result = re.ReplaceAllString(result, "FULL JOIN")
+		// LEFT/RIGHT/FULL OUTER JOIN → LEFT/RIGHT/FULL JOIN (OUTER is redundant)
+		if (t.is("LEFT") || t.is("RIGHT") || t.is("FULL")) &&
+			i+2 < len(tokens) && tokens[i+1].is("OUTER") && tokens[i+2].is("JOIN") {
+			result = append(result,
+				newToken(tokenIdent, strings.ToUpper(t.text), t.space),
+				newToken(tokenIdent, "JOIN", true),
+			)
+			i += 2
+			continue
+		}
 
+		result = append(result, t)
+	}
 	return result
 }
 
 // normalizeOrderByAsc removes redundant ASC in ORDER BY clauses.
 // ASC is the default sort order, so "ORDER BY x ASC" is equivalent to "ORDER BY x".
-func normalizeOrderByAsc(query string) string {
-	// Match ORDER BY column ASC patterns
-	// This handles:
-	// - ORDER BY x ASC → ORDER BY x
-	// - ORDER BY x ASC, y DESC → ORDER BY x, y DESC
-	// - ORDER BY x ASC, y ASC → ORDER BY x, y
-
-	// Remove ASC followed by comma (with optional whitespace)
-	re := regexp.MustCompile(`(?i)\s+ASC\s*,`)
-	result := re.ReplaceAllString(query, ",")
-
-	// Remove ASC at end of query or before LIMIT/OFFSET/etc
-	re = regexp.MustCompile(`(?i)\s+ASC(\s*$|\s+LIMIT\b|\s+OFFSET\b|\s+HAVING\b|\s+UNION\b|\s*\))`)
-	result = re.ReplaceAllString(result, "$1")
-
-	// Clean up any double spaces that might result
-	re = regexp.MustCompile(`\s+`)
-	result = re.ReplaceAllString(result, " ")
-
+// This handles:
+// - ORDER BY x ASC → ORDER BY x
+// - ORDER BY x ASC, y DESC → ORDER BY x, y DESC
+// - ORDER BY x ASC, y ASC → ORDER BY x, y
+func normalizeOrderByAsc(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.is("ASC") {
+			continue
+		}
+		result = append(result, t)
+	}
 	return result
 }
 
@@ -62,15 +60,18 @@ func normalizeOrderByAsc(query string) string {
 //   - INSERT INTO users (name) VALUES (?) RETURNING id → INSERT INTO users (name) VALUES (?)
 //   - UPDATE users SET name = ? WHERE id = ? RETURNING * → UPDATE users SET name = ? WHERE id = ?
 //   - DELETE FROM users WHERE id = ? RETURNING id, name → DELETE FROM users WHERE id = ?
-func removeReturningClause(query string) string {
-	// Match RETURNING clause and everything after it (column list)
-	// The RETURNING clause can contain:
-	// - Single column: RETURNING id
-	// - Multiple columns: RETURNING id, name, email
-	// - Star: RETURNING *
-	// - Expressions: RETURNING id, created_at
-	re := regexp.MustCompile(`(?i)\s+RETURNING\s+.+$`)
-	return re.ReplaceAllString(query, "")
+//
+// The clause ends at the end of the statement or of the enclosing parentheses (CTEs).
+func removeReturningClause(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].is("RETURNING") {
+			i = clauseEnd(tokens, i+1, func(token) bool { return false }) - 1
+			continue
+		}
+		result = append(result, tokens[i])
+	}
+	return result
 }
 
 // normalizeTableQualifiers removes redundant table qualifiers from simple queries.
@@ -82,58 +83,55 @@ func removeReturningClause(query string) string {
 // Queries with JOINs or subqueries are left unchanged to avoid ambiguity:
 //   - SELECT * FROM users JOIN orders ON users.id = orders.user_id WHERE users.age >= ? → unchanged
 //   - SELECT * FROM users WHERE users.id IN (SELECT user_id FROM orders) → unchanged
-func normalizeTableQualifiers(query string) string {
-	upperQuery := strings.ToUpper(query)
-
+func normalizeTableQualifiers(tokens []token) []token {
 	// Check for JOINs - if present, don't normalize (need qualifiers for disambiguation)
-	if hasJoin(upperQuery) {
-		return query
+	if hasJoin(tokens) {
+		return tokens
 	}
 
 	// Check for subqueries - if present, don't normalize
-	if hasSubquery(upperQuery) {
-		return query
+	if hasSubquery(tokens) {
+		return tokens
 	}
 
 	// Check for multiple tables (comma join) - if present, don't normalize
-	if hasMultipleTables(upperQuery) {
-		return query
+	if hasMultipleTables(tokens) {
+		return tokens
 	}
 
 	// Check for schema-qualified table name - if present, don't normalize
-	if hasSchemaPrefix(query) {
-		return query
+	if hasSchemaPrefix(tokens) {
+		return tokens
 	}
 
 	// Extract table name from the query
-	tableName := extractTableName(query)
+	tableName := extractTableName(tokens)
 	if tableName == "" {
-		return query
+		return tokens
 	}
 
 	// Remove table qualifier (tablename.) from column references
 	// Match: tablename.columnname (case-insensitive for table name)
-	// Be careful not to match inside string literals
-	pattern := `(?i)\b` + regexp.QuoteMeta(tableName) + `\.(\w+)`
-	re := regexp.MustCompile(pattern)
-	return re.ReplaceAllString(query, "$1")
+	result := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.isName() && strings.EqualFold(t.name(), tableName) &&
+			i+2 < len(tokens) && tokens[i+1].isPunct(".") && tokens[i+2].isName() {
+			column := tokens[i+2]
+			column.space = t.space
+			result = append(result, column)
+			i += 2
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
 }
 
 // hasJoin checks if the query contains any JOIN clause.
-func hasJoin(upperQuery string) bool {
-	// Check for various JOIN types
-	joinPatterns := []string{
-		" JOIN ",
-		" LEFT JOIN ",
-		" RIGHT JOIN ",
-		" INNER JOIN ",
-		" OUTER JOIN ",
-		" CROSS JOIN ",
-		" FULL JOIN ",
-		" NATURAL JOIN ",
-	}
-	for _, pattern := range joinPatterns {
-		if strings.Contains(upperQuery, pattern) {
+func hasJoin(tokens []token) bool {
+	for _, t := range tokens {
+		if t.is("JOIN") {
 			return true
 		}
 	}
@@ -141,15 +139,10 @@ func hasJoin(upperQuery string) bool {
 }
 
 // hasSubquery checks if the query contains a subquery.
-func hasSubquery(upperQuery string) bool {
-	// Look for SELECT within parentheses (common subquery patterns)
-	// Patterns: (SELECT, IN (SELECT, EXISTS (SELECT, etc.
-	subqueryPatterns := []string{
-		"(SELECT ",
-		"( SELECT ",
-	}
-	for _, pattern := range subqueryPatterns {
-		if strings.Contains(upperQuery, pattern) {
+// Patterns: (SELECT, IN (SELECT, EXISTS (SELECT, etc.
+func hasSubquery(tokens []token) bool {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].isPunct("(") && (tokens[i+1].is("SELECT") || tokens[i+1].is("WITH")) {
 			return true
 		}
 	}
@@ -157,41 +150,59 @@ func hasSubquery(upperQuery string) bool {
 }
 
 // hasMultipleTables checks if the query has multiple tables (comma join).
-func hasMultipleTables(upperQuery string) bool {
-	// Look for comma-separated tables in FROM clause
-	// Pattern: FROM table1, table2 or FROM table1 , table2
-	re := regexp.MustCompile(`\bFROM\s+\w+\s*,`)
-	return re.MatchString(upperQuery)
+// Pattern: FROM table1, table2 or FROM table1 t1, table2 t2
+func hasMultipleTables(tokens []token) bool {
+	for i, t := range tokens {
+		if !t.is("FROM") {
+			continue
+		}
+		end := clauseEnd(tokens, i+1, isClauseKeyword)
+		for _, ft := range tokens[i+1 : end] {
+			if ft.isPunct(",") {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // hasSchemaPrefix checks if the query has schema-qualified table names.
-func hasSchemaPrefix(query string) bool {
-	// Look for schema.table pattern in FROM/UPDATE/INSERT INTO clause
-	// Pattern: FROM schema.table or UPDATE schema.table
-	re := regexp.MustCompile(`(?i)\b(FROM|UPDATE|INSERT\s+INTO)\s+\w+\.\w+`)
-	return re.MatchString(query)
+// Pattern: FROM schema.table, UPDATE schema.table or INSERT INTO schema.table
+func hasSchemaPrefix(tokens []token) bool {
+	for i := range tokens {
+		if start := tableNameStart(tokens, i); start >= 0 {
+			if start+1 < len(tokens) && tokens[start+1].isPunct(".") {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // extractTableName extracts the main table name from a SQL query.
-func extractTableName(query string) string {
-	// Try to match FROM clause for SELECT/DELETE
-	// Pattern: FROM tablename (with optional schema)
-	fromRe := regexp.MustCompile(`(?i)\bFROM\s+(\w+)`)
-	if matches := fromRe.FindStringSubmatch(query); len(matches) >= 2 {
-		return matches[1]
-	}
-
-	// Try to match UPDATE tablename
-	updateRe := regexp.MustCompile(`(?i)\bUPDATE\s+(\w+)`)
-	if matches := updateRe.FindStringSubmatch(query); len(matches) >= 2 {
-		return matches[1]
+// FROM (SELECT/DELETE) takes precedence over UPDATE and INSERT INTO.
+func extractTableName(tokens []token) string {
+	for _, keyword := range []string{"FROM", "UPDATE", "INTO"} {
+		for i, t := range tokens {
+			if !t.is(keyword) {
+				continue
+			}
+			if start := tableNameStart(tokens, i); start >= 0 {
+				return tokens[start].name()
+			}
+		}
 	}
+	return ""
+}
 
-	// Try to match INSERT INTO tablename
-	insertRe := regexp.MustCompile(`(?i)\bINSERT\s+INTO\s+(\w+)`)
-	if matches := insertRe.FindStringSubmatch(query); len(matches) >= 2 {
-		return matches[1]
+// tableNameStart returns the index of the table name following a FROM, UPDATE
+// or INSERT INTO keyword at i, or -1.
+func tableNameStart(tokens []token, i int) int {
+	t := tokens[i]
+	isTableKeyword := t.is("FROM") || t.is("UPDATE") ||
+		(t.is("INTO") && i > 0 && tokens[i-1].is("INSERT"))
+	if !isTableKeyword || i+1 >= len(tokens) || !tokens[i+1].isName() {
+		return -1
 	}
-
-	return ""
+	return i + 1
 }
@@ -0,0 +1,104 @@
+package normalizer
+
+import (
+	"strings"
+)
+
+// Tables returns the tables a query reads or writes in order of appearance,
+// without duplicates and including the tables of nested statements.
+// Names are unquoted; schema-qualified names keep their schema.
+// SELECT * FROM users u JOIN orders o ON ... → [users orders]
+func (n *Normalizer) Tables(query string) []string {
+	tokens := n.normalizedTokens(query)
+
+	var tables []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			tables = append(tables, name)
+		}
+	}
+
+	// statement[d] reports whether the parentheses at depth d hold a statement,
+	// so that the FROM of EXTRACT(x FROM y) is not mistaken for a table list
+	statement := []bool{true}
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			nested := i+1 < len(tokens) && (tokens[i+1].is("SELECT") || tokens[i+1].is("WITH"))
+			statement = append(statement, nested)
+		case t.isPunct(")"):
+			if len(statement) > 1 {
+				statement = statement[:len(statement)-1]
+			}
+		case statement[len(statement)-1] && startsTableList(tokens, i):
+			for _, name := range tableList(tokens, i+1, t.is("FROM") || t.is("UPDATE"), n.spec.keywords) {
+				add(name)
+			}
+		}
+	}
+	return tables
+}
+
+// startsTableList reports whether the keyword at i is followed by table names:
+// FROM, JOIN, INTO or the UPDATE of an UPDATE statement.
+func startsTableList(tokens []token, i int) bool {
+	t := tokens[i]
+	prev := token{}
+	if i > 0 {
+		prev = tokens[i-1]
+	}
+
+	switch {
+	case t.is("FROM"):
+		// IS [NOT] DISTINCT FROM is an operator
+		return !prev.is("DISTINCT")
+	case t.is("JOIN"), t.is("STRAIGHT_JOIN"), t.is("INTO"):
+		return true
+	case t.is("UPDATE"):
+		// FOR UPDATE, ON DUPLICATE KEY UPDATE and DO UPDATE SET are not statements
+		return !prev.is("FOR") && !prev.is("KEY") && !prev.is("DO")
+	}
+	return false
+}
+
+// tableList returns the table names starting at start, skipping aliases.
+// With list set, comma-separated tables are read as well (FROM a, b).
+func tableList(tokens []token, start int, list bool, keywords map[string]bool) []string {
+	var names []string
+	i := start
+	for i < len(tokens) {
+		if tokens[i].is("ONLY") {
+			i++
+		}
+		end := scanName(tokens, i)
+		if end == i || (tokens[i].kind == tokenIdent && keywords[strings.ToUpper(tokens[i].text)]) {
+			break
+		}
+
+		parts := make([]string, 0, 2)
+		for _, t := range tokens[i:end] {
+			if t.isName() {
+				parts = append(parts, t.name())
+			}
+		}
+		names = append(names, strings.Join(parts, "."))
+
+		// Skip the alias
+		i = end
+		if i < len(tokens) && tokens[i].is("AS") {
+			i++
+		}
+		if i < len(tokens) && tokens[i].isName() &&
+			(tokens[i].kind == tokenQuotedIdent || !keywords[strings.ToUpper(tokens[i].text)]) {
+			i++
+		}
+
+		if !list || i >= len(tokens) || !tokens[i].isPunct(",") {
+			break
+		}
+		i++
+	}
+	return names
+}
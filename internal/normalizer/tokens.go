@@ -0,0 +1,119 @@
+package normalizer
+
+// matchingParen returns the index of the parenthesis closing the one at open, or -1.
+func matchingParen(tokens []token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isPunct("("):
+			depth++
+		case tokens[i].isPunct(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// clauseEnd returns the index of the first token at or after start that is at
+// the same nesting depth and satisfies stop, or the end of the enclosing
+// parenthesis, statement or query.
+func clauseEnd(tokens []token, start int, stop func(token) bool) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case depth == 0 && (t.isPunct(";") || stop(t)):
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+// splitTopLevel splits tokens at commas outside of parentheses.
+func splitTopLevel(tokens []token) [][]token {
+	var parts [][]token
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case t.isPunct(",") && depth == 0:
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// joinList joins items with ", " separators, normalizing their spacing.
+func joinList(items [][]token, leadingSpace bool) []token {
+	var result []token
+	for i, item := range items {
+		if i > 0 {
+			result = append(result, newToken(tokenPunct, ",", false))
+		}
+		result = append(result, withSpace(item, i > 0 || leadingSpace)...)
+	}
+	return result
+}
+
+// withSpace returns a copy of tokens whose first token has the given space flag.
+func withSpace(tokens []token, space bool) []token {
+	result := make([]token, len(tokens))
+	copy(result, tokens)
+	if len(result) > 0 {
+		result[0].space = space
+	}
+	return result
+}
+
+// newToken creates a token that does not refer to a bind argument.
+func newToken(kind tokenKind, text string, space bool) token {
+	return token{kind: kind, text: text, space: space, arg: -1}
+}
+
+// isClauseKeyword reports whether the token starts a clause that ends a
+// FROM, SET or WHERE clause.
+func isClauseKeyword(t token) bool {
+	for _, kw := range []string{
+		"WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "FETCH",
+		"RETURNING", "UNION", "INTERSECT", "EXCEPT", "ON", "WINDOW", "FOR",
+	} {
+		if t.is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanName returns the end of the possibly qualified name (schema.table) starting at start,
+// or start if there is no name.
+func scanName(tokens []token, start int) int {
+	i := start
+	for i < len(tokens) && tokens[i].isName() {
+		i++
+		if i+1 < len(tokens) && tokens[i].isPunct(".") && tokens[i+1].isName() {
+			i++
+			continue
+		}
+		break
+	}
+	return i
+}
+
+// renderKey renders tokens for sorting and lookups, ignoring leading space.
+func renderKey(tokens []token) string {
+	return render(withSpace(tokens, false))
+}
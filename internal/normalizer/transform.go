@@ -1,116 +1,52 @@
 package normalizer
 
 import (
-	"fmt"
-	"regexp"
 	"strings"
 )
 
-// normalizeWhitespace normalizes whitespace in the query.
-func normalizeWhitespace(query string) string {
-	// Replace all whitespace sequences with a single space
-	re := regexp.MustCompile(`\s+`)
-	return re.ReplaceAllString(query, " ")
-}
-
 // removeComments removes SQL comments from the query.
-func removeComments(query string) string {
-	// Remove single-line comments (-- ...)
-	re := regexp.MustCompile(`--[^\n]*`)
-	result := re.ReplaceAllString(query, "")
-
-	// Remove multi-line comments (/* ... */)
-	re = regexp.MustCompile(`/\*[\s\S]*?\*/`)
-	result = re.ReplaceAllString(result, "")
-
+// A removed comment separates the surrounding tokens like whitespace.
+func removeComments(tokens []token) []token {
+	result := make([]token, 0, len(tokens))
+	space := false
+	for _, t := range tokens {
+		if t.kind == tokenComment {
+			space = true
+			continue
+		}
+		if space {
+			t.space = true
+			space = false
+		}
+		result = append(result, t)
+	}
 	return result
 }
 
 // removeQuotes removes identifier quotes (backticks, double quotes, brackets).
-func removeQuotes(query string) string {
-	result := query
-
-	// Remove backticks (MySQL)
-	re := regexp.MustCompile("`([^`]+)`")
-	result = re.ReplaceAllString(result, "$1")
-
-	// Remove double quotes (PostgreSQL, standard SQL)
-	re = regexp.MustCompile(`"([^"]+)"`)
-	result = re.ReplaceAllString(result, "$1")
-
-	// Remove brackets (SQL Server)
-	re = regexp.MustCompile(`\[([^\]]+)\]`)
-	result = re.ReplaceAllString(result, "$1")
-
-	return result
-}
-
-// removeQuotesPreservingCase removes quotes and uppercases keywords,
-// but preserves the original case of quoted identifiers.
-// This prevents column names like "count" from being uppercased to COUNT.
-func removeQuotesPreservingCase(query string) string {
-	// Step 1: Extract all quoted identifiers and replace with placeholders
-	placeholders := make(map[string]string)
-	counter := 0
-	result := query
-
-	// Process backticks (MySQL)
-	reBacktick := regexp.MustCompile("`([^`]+)`")
-	result = reBacktick.ReplaceAllStringFunc(result, func(match string) string {
-		inner := reBacktick.FindStringSubmatch(match)[1]
-		placeholder := fmt.Sprintf("__QUOTED_%d__", counter)
-		placeholders[placeholder] = inner
-		counter++
-		return placeholder
-	})
-
-	// Process double quotes (PostgreSQL, standard SQL)
-	reDoubleQuote := regexp.MustCompile(`"([^"]+)"`)
-	result = reDoubleQuote.ReplaceAllStringFunc(result, func(match string) string {
-		inner := reDoubleQuote.FindStringSubmatch(match)[1]
-		placeholder := fmt.Sprintf("__QUOTED_%d__", counter)
-		placeholders[placeholder] = inner
-		counter++
-		return placeholder
-	})
-
-	// Process brackets (SQL Server)
-	reBracket := regexp.MustCompile(`\[([^\]]+)\]`)
-	result = reBracket.ReplaceAllStringFunc(result, func(match string) string {
-		inner := reBracket.FindStringSubmatch(match)[1]
-		placeholder := fmt.Sprintf("__QUOTED_%d__", counter)
-		placeholders[placeholder] = inner
-		counter++
-		return placeholder
-	})
-
-	// Step 2: Uppercase keywords (only affects non-quoted parts)
-	result = uppercaseKeywords(result)
-
-	// Step 3: Replace placeholders with original identifiers (without quotes)
-	for placeholder, original := range placeholders {
-		result = strings.Replace(result, placeholder, original, 1)
+// Unquoted identifiers keep their token kind, so that their original case is
+// preserved when keywords are uppercased. This prevents column names like
+// "count" from being uppercased to COUNT.
+func removeQuotes(tokens []token) []token {
+	result := make([]token, len(tokens))
+	for i, t := range tokens {
+		if t.kind == tokenQuotedIdent {
+			t.text = t.name()
+		}
+		result[i] = t
 	}
-
 	return result
 }
 
 // unifyPlaceholders converts various placeholder formats to ?.
-func unifyPlaceholders(query string) string {
-	result := query
-
-	// Convert $1, $2, ... (PostgreSQL) to ?
-	re := regexp.MustCompile(`\$\d+`)
-	result = re.ReplaceAllString(result, "?")
-
-	// Convert :name (named parameters) to ?
-	re = regexp.MustCompile(`:(\w+)`)
-	result = re.ReplaceAllString(result, "?")
-
-	// Convert @name (SQL Server parameters) to ?
-	re = regexp.MustCompile(`@(\w+)`)
-	result = re.ReplaceAllString(result, "?")
-
+func unifyPlaceholders(tokens []token) []token {
+	result := make([]token, len(tokens))
+	for i, t := range tokens {
+		if t.kind == tokenPlaceholder {
+			t.text = "?"
+		}
+		result[i] = t
+	}
 	return result
 }
 
@@ -126,15 +62,62 @@ var sqlKeywords = []string{
 	"LIKE", "BETWEEN", "EXISTS", "CASE", "WHEN", "THEN", "ELSE", "END",
 	"COUNT", "SUM", "AVG", "MIN", "MAX", "COALESCE", "NULLIF",
 	"TRUE", "FALSE", "RETURNING",
+	"BEGIN", "COMMIT", "ROLLBACK", "TRANSACTION",
 }
 
 // uppercaseKeywords converts SQL keywords to uppercase.
-func uppercaseKeywords(query string) string {
-	result := query
-	for _, keyword := range sqlKeywords {
-		// Use word boundary to avoid replacing partial words
-		re := regexp.MustCompile(`(?i)\b` + keyword + `\b`)
-		result = re.ReplaceAllString(result, keyword)
+// Quoted identifiers, literals and qualified names (users.count) are left untouched.
+func uppercaseKeywords(tokens []token, keywords map[string]bool) []token {
+	result := make([]token, len(tokens))
+	for i, t := range tokens {
+		if t.kind == tokenIdent && !isQualified(tokens, i) {
+			if upper := strings.ToUpper(t.text); keywords[upper] {
+				t.text = upper
+			}
+		}
+		result[i] = t
+	}
+	return result
+}
+
+// comparisonOperators lists the operators spaced by spaceOperators.
+var comparisonOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "<=>": true,
+}
+
+// spaceOperators surrounds comparison operators with spaces, as query builders
+// differ in whether they write them: "id"=$1 → "id" = $1
+func spaceOperators(tokens []token) []token {
+	result := make([]token, len(tokens))
+	for i, t := range tokens {
+		if i > 0 && (isComparison(t) || isComparison(tokens[i-1])) {
+			t.space = true
+		}
+		result[i] = t
 	}
 	return result
 }
+
+// isComparison reports whether t is a comparison operator.
+func isComparison(t token) bool {
+	return t.kind == tokenOperator && comparisonOperators[t.text]
+}
+
+// foldIdentifiers converts unquoted identifiers other than keywords to lowercase,
+// for dialects where unquoted identifiers are case-insensitive.
+// Quoted identifiers keep their case, so "Users" and users stay distinct.
+func foldIdentifiers(tokens []token, keywords map[string]bool) []token {
+	result := make([]token, len(tokens))
+	for i, t := range tokens {
+		if t.kind == tokenIdent && (isQualified(tokens, i) || !keywords[strings.ToUpper(t.text)]) {
+			t.text = strings.ToLower(t.text)
+		}
+		result[i] = t
+	}
+	return result
+}
+
+// isQualified reports whether the token at i is part of a dotted name.
+func isQualified(tokens []token, i int) bool {
+	return (i > 0 && tokens[i-1].isPunct(".")) || (i+1 < len(tokens) && tokens[i+1].isPunct("."))
+}
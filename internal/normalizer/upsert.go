@@ -0,0 +1,235 @@
+package normalizer
+
+import (
+	"sort"
+	"strings"
+)
+
+// normalizeUpserts rewrites the upsert forms of INSERT statements to
+// INSERT INTO ... ON CONFLICT [target] DO NOTHING | DO UPDATE SET ..., with the
+// conflict target columns and the assignments sorted, and references to the
+// proposed row written as EXCLUDED.col:
+// ON DUPLICATE KEY UPDATE b = VALUES(b), a = ? → ON CONFLICT DO UPDATE SET a = ?, b = EXCLUDED.b
+// VALUES (?) AS new ON DUPLICATE KEY UPDATE a = new.a → VALUES (?) ON CONFLICT DO UPDATE SET a = EXCLUDED.a
+// ON DUPLICATE KEY UPDATE id = id → ON CONFLICT DO NOTHING
+// ON CONFLICT (b, a) DO UPDATE SET a = excluded.a → ON CONFLICT (a, b) DO UPDATE SET a = EXCLUDED.a
+// INSERT IGNORE INTO t ... / INSERT OR IGNORE INTO t ... → INSERT INTO t ... ON CONFLICT DO NOTHING
+// INSERT OR REPLACE INTO t (a) ... / REPLACE INTO t (a) ... → INSERT INTO t (a) ... ON CONFLICT DO UPDATE SET a = EXCLUDED.a
+// MySQL and SQLite without a target do not name the conflicting key, so their
+// upserts have no conflict target.
+func normalizeUpserts(tokens []token) []token {
+	// INSERT [IGNORE | OR IGNORE | OR REPLACE] INTO, REPLACE INTO
+	var ignore, replace bool
+	into := 1
+	switch {
+	case len(tokens) > 3 && tokens[0].is("INSERT") && tokens[1].is("OR") && tokens[3].is("INTO"):
+		ignore, replace = tokens[2].is("IGNORE"), tokens[2].is("REPLACE")
+		if !ignore && !replace {
+			return tokens
+		}
+		into = 3
+	case len(tokens) > 2 && tokens[0].is("INSERT") && tokens[1].is("IGNORE") && tokens[2].is("INTO"):
+		ignore, into = true, 2
+	case len(tokens) > 1 && tokens[0].is("REPLACE") && tokens[1].is("INTO"):
+		replace = true
+	case len(tokens) > 1 && tokens[0].is("INSERT") && tokens[1].is("INTO"):
+	default:
+		return tokens
+	}
+
+	conflict, end := upsertClauses(tokens, into+1)
+	body := tokens[into:conflict]
+
+	var target []token
+	var assignments []assignment
+	var tail []token // WHERE condition of DO UPDATE
+	nothing := false
+	switch {
+	case conflict < end && tokens[conflict+1].is("DUPLICATE"):
+		// ON DUPLICATE KEY UPDATE assignments, with an optional row alias before
+		alias := ""
+		if n := len(body); n > 2 && body[n-2].is("AS") && body[n-1].isName() {
+			alias = body[n-1].name()
+			body = body[:n-2]
+		}
+		assignments = parseSetAssignments(tokens[conflict+4 : end])
+		if len(assignments) == 0 {
+			return tokens
+		}
+		for k := range assignments {
+			assignments[k].value = excludedReferences(assignments[k].value, alias)
+		}
+	case conflict < end:
+		// ON CONFLICT [target] DO NOTHING | DO UPDATE SET assignments [WHERE condition]
+		do := clauseEnd(tokens, conflict+2, func(t token) bool { return t.is("DO") })
+		if do+1 >= end {
+			return tokens
+		}
+		target = sortConflictTarget(tokens[conflict+2 : do])
+		switch {
+		case tokens[do+1].is("NOTHING"):
+			nothing = true
+		case tokens[do+1].is("UPDATE") && do+2 < end && tokens[do+2].is("SET"):
+			setEnd := clauseEnd(tokens, do+3, isClauseKeyword)
+			if setEnd > end {
+				setEnd = end
+			}
+			assignments = parseSetAssignments(tokens[do+3 : setEnd])
+			if len(assignments) == 0 {
+				return tokens
+			}
+			for k := range assignments {
+				assignments[k].value = excludedReferences(assignments[k].value, "")
+			}
+			tail = tokens[setEnd:end]
+		default:
+			return tokens
+		}
+	case ignore:
+		nothing = true
+	case replace:
+		columns := insertColumns(tokens, into)
+		if columns == nil {
+			return tokens
+		}
+		for _, c := range columns {
+			assignments = append(assignments, assignment{column: []token{c}, value: excluded(c, true)})
+		}
+	default:
+		return tokens
+	}
+
+	if len(assignments) > 0 && allNoop(assignments) {
+		assignments, tail, nothing = nil, nil, true
+	}
+	sortAssignments(assignments)
+
+	result := make([]token, 0, len(tokens)+8)
+	result = append(result, newToken(tokenIdent, "INSERT", tokens[0].space))
+	result = append(result, body...)
+	result = append(result, newToken(tokenIdent, "ON", true), newToken(tokenIdent, "CONFLICT", true))
+	result = append(result, withSpace(target, true)...)
+	result = append(result, newToken(tokenIdent, "DO", true))
+	if nothing {
+		result = append(result, newToken(tokenIdent, "NOTHING", true))
+	} else {
+		result = append(result, newToken(tokenIdent, "UPDATE", true), newToken(tokenIdent, "SET", true))
+		result = append(result, joinAssignments(assignments)...)
+		result = append(result, tail...)
+	}
+	return append(result, tokens[end:]...)
+}
+
+// upsertClauses returns the index of the ON CONFLICT or ON DUPLICATE KEY
+// UPDATE clause of the INSERT statement body starting at start, and the end
+// of the statement before RETURNING or a trailing semicolon. The clause index
+// equals the end if there is no conflict clause.
+func upsertClauses(tokens []token, start int) (conflict, end int) {
+	end = len(tokens)
+	for end > start && tokens[end-1].isPunct(";") {
+		end--
+	}
+	conflict = -1
+	for i := start; i < end; i++ {
+		switch {
+		case tokens[i].isPunct("("):
+			if closeIdx := matchingParen(tokens, i); closeIdx > i {
+				i = closeIdx
+			}
+		case tokens[i].is("RETURNING"):
+			end = i
+		case conflict < 0 && tokens[i].is("ON") && i+1 < len(tokens) && tokens[i+1].is("CONFLICT"):
+			conflict = i
+		case conflict < 0 && i+3 < len(tokens) && tokens[i].is("ON") && tokens[i+1].is("DUPLICATE") &&
+			tokens[i+2].is("KEY") && tokens[i+3].is("UPDATE"):
+			conflict = i
+		}
+	}
+	if conflict < 0 {
+		conflict = end
+	}
+	return conflict, end
+}
+
+// sortConflictTarget sorts the column list of an ON CONFLICT target:
+// (b, a) WHERE ... → (a, b) WHERE ...
+// Targets naming a constraint are returned unchanged.
+func sortConflictTarget(target []token) []token {
+	if len(target) == 0 || !target[0].isPunct("(") {
+		return target
+	}
+	closeIdx := matchingParen(target, 0)
+	if closeIdx < 0 {
+		return target
+	}
+	columns := splitTopLevel(target[1:closeIdx])
+	sort.SliceStable(columns, func(a, b int) bool {
+		return renderKey(columns[a]) < renderKey(columns[b])
+	})
+
+	result := append([]token{target[0]}, joinList(columns, false)...)
+	return append(result, target[closeIdx:]...)
+}
+
+// excludedReferences rewrites references to the proposed row in an assignment
+// value to EXCLUDED.col: VALUES(col), excluded.col and alias.col for the
+// row alias of MySQL 8 upserts.
+func excludedReferences(value []token, alias string) []token {
+	result := make([]token, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		t := value[i]
+		switch {
+		case t.is("VALUES") && i+3 < len(value) && value[i+1].isPunct("(") && value[i+2].isName() && value[i+3].isPunct(")"):
+			result = append(result, excluded(value[i+2], t.space)...)
+			i += 3
+		case t.isName() && i+2 < len(value) && value[i+1].isPunct(".") && value[i+2].isName() &&
+			(strings.EqualFold(t.name(), "excluded") || (alias != "" && strings.EqualFold(t.name(), alias))):
+			result = append(result, excluded(value[i+2], t.space)...)
+			i += 2
+		default:
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// excluded returns the tokens of EXCLUDED.col.
+func excluded(column token, space bool) []token {
+	column.space = false
+	return []token{newToken(tokenIdent, "EXCLUDED", space), newToken(tokenPunct, ".", false), column}
+}
+
+// allNoop reports whether every assignment sets a column to itself, as in
+// ON DUPLICATE KEY UPDATE id = id, which GORM emits to ignore conflicts.
+func allNoop(assignments []assignment) bool {
+	for _, a := range assignments {
+		if len(a.column) != 1 || len(a.value) == 0 || scanName(a.value, 0) != len(a.value) ||
+			!strings.EqualFold(a.value[len(a.value)-1].name(), a.column[0].name()) ||
+			strings.EqualFold(a.value[0].name(), "excluded") {
+			return false
+		}
+	}
+	return true
+}
+
+// insertColumns returns the column list of the INSERT ... INTO at into,
+// or nil if the statement has none.
+func insertColumns(tokens []token, into int) []token {
+	open := scanName(tokens, into+1)
+	if open >= len(tokens) || !tokens[open].isPunct("(") {
+		return nil
+	}
+	closeIdx := matchingParen(tokens, open)
+	if closeIdx < 0 {
+		return nil
+	}
+
+	var columns []token
+	for _, column := range splitTopLevel(tokens[open+1 : closeIdx]) {
+		if len(column) != 1 || !column[0].isName() {
+			return nil
+		}
+		columns = append(columns, column[0])
+	}
+	return columns
+}
@@ -0,0 +1,102 @@
+package migratiorm
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ucpr/migratiorm/internal/comparator"
+	"github.com/ucpr/migratiorm/internal/normalizer"
+)
+
+// ArgMatcher decides whether an actual bind argument matches the expected one.
+// Matchers let non-deterministic values such as time.Now() or generated UUIDs
+// compare equal between the Expect and Actual runs.
+type ArgMatcher = comparator.ArgMatcher
+
+// AnyArg returns a matcher that accepts any value.
+func AnyArg() ArgMatcher {
+	return comparator.AnyArg()
+}
+
+// AnyArgOfType returns a matcher that accepts values of the same type as sample on both sides.
+// Equivalent driver types are treated as equal, so AnyArgOfType(0) also accepts int64 values.
+func AnyArgOfType(sample any) ArgMatcher {
+	return comparator.AnyArgOfType(sample)
+}
+
+// TimeWithin returns a matcher that accepts two time.Time values at most tolerance apart.
+func TimeWithin(tolerance time.Duration) ArgMatcher {
+	return comparator.TimeWithin(tolerance)
+}
+
+// MatchRegexp returns a matcher that accepts string values matching pattern on both sides.
+// It panics if pattern is not a valid regular expression.
+func MatchRegexp(pattern string) ArgMatcher {
+	return comparator.MatchRegexp(regexp.MustCompile(pattern))
+}
+
+// MatchFunc returns a matcher backed by a custom comparison function.
+func MatchFunc(fn func(expected, actual any) bool) ArgMatcher {
+	return comparator.MatchFunc(fn)
+}
+
+// argPosition identifies a bind argument of an expected query.
+type argPosition struct {
+	query int
+	arg   int
+}
+
+// MatchArg attaches a matcher to a bind argument of an expected query.
+// query is the index of the query in ExpectedQueries and arg the index
+// of the argument in its NormalizedArgs.
+func MatchArg(query, arg int, m ArgMatcher) AssertOption {
+	return func(o *assertOptions) {
+		if o.positionMatchers == nil {
+			o.positionMatchers = make(map[argPosition]ArgMatcher)
+		}
+		o.positionMatchers[argPosition{query: query, arg: arg}] = m
+	}
+}
+
+// MatchColumn attaches a matcher to every bind argument bound to the named column,
+// such as created_at in "WHERE created_at > ?" or "INSERT INTO t (created_at) VALUES (?)".
+// Column names are compared case-insensitively and without table qualifiers.
+func MatchColumn(column string, m ArgMatcher) AssertOption {
+	return func(o *assertOptions) {
+		if o.columnMatchers == nil {
+			o.columnMatchers = make(map[string]ArgMatcher)
+		}
+		o.columnMatchers[strings.ToLower(column)] = m
+	}
+}
+
+// argMatchers resolves the matchers for the bind arguments of an expected query.
+// Position matchers take precedence over column matchers.
+func (o assertOptions) argMatchers(n *normalizer.Normalizer, index int, q Query) []ArgMatcher {
+	if len(o.positionMatchers) == 0 && len(o.columnMatchers) == 0 {
+		return nil
+	}
+
+	matchers := make([]ArgMatcher, len(q.NormalizedArgs))
+
+	if len(o.columnMatchers) > 0 {
+		columns := n.PlaceholderColumns(q.Normalized)
+		// Columns can only be attributed when every argument has a placeholder
+		if len(columns) == len(matchers) {
+			for i, col := range columns {
+				if m, ok := o.columnMatchers[strings.ToLower(col)]; ok {
+					matchers[i] = m
+				}
+			}
+		}
+	}
+
+	for i := range matchers {
+		if m, ok := o.positionMatchers[argPosition{query: index, arg: i}]; ok {
+			matchers[i] = m
+		}
+	}
+
+	return matchers
+}
@@ -2,6 +2,10 @@ package migratiorm
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
 	"testing"
 
 	"github.com/ucpr/migratiorm/internal/capturer"
@@ -11,11 +15,17 @@ import (
 
 // Migratiorm is the main interface for comparing SQL queries between ORMs.
 type Migratiorm struct {
-	options    options
-	normalizer *normalizer.Normalizer
-	comparator *comparator.Comparator
-	expected   []Query
-	actual     []Query
+	options          options
+	normalizer       *normalizer.Normalizer // Normalizer of expected queries
+	actualNormalizer *normalizer.Normalizer // Normalizer of actual queries
+	expected         []Query
+	actual           []Query
+	expectRan        bool // Whether Expect or ExpectResult was called
+	expectErr        error
+	actualErr        error
+
+	expectEffects effects
+	actualEffects effects
 }
 
 // New creates a new Migratiorm instance with the given options.
@@ -26,59 +36,135 @@ func New(opts ...Option) *Migratiorm {
 	}
 
 	return &Migratiorm{
-		options:    o,
-		normalizer: normalizer.New(o.normalizerOptions),
-		comparator: comparator.New(o.compareMode),
-		expected:   make([]Query, 0),
-		actual:     make([]Query, 0),
+		options:          o,
+		normalizer:       normalizer.New(o.sideNormalizerOptions(ExpectedSide)),
+		actualNormalizer: normalizer.New(o.sideNormalizerOptions(ActualSide)),
+		expected:         make([]Query, 0),
+		actual:           make([]Query, 0),
 	}
 }
 
 // Expect captures queries from the expected (source) ORM.
 // The callback receives a *sql.DB that should be passed to the ORM.
+// Capture setup failures and panics in the callback are reported by Assert.
 func (m *Migratiorm) Expect(fn func(db *sql.DB)) {
-	cap, err := capturer.New(m.normalizer)
-	if err != nil {
-		// Store error state - will be reported during Assert
-		return
-	}
-	defer cap.Close() //nolint:errcheck
-
-	fn(cap.DB())
-
-	m.expected = m.buildQueries(cap.RawQueries())
+	m.expected, m.expectEffects, m.expectErr = m.capture(m.normalizer, discardResult(fn), false)
+	m.expectRan = true
 }
 
 // Actual captures queries from the actual (target) ORM.
 // The callback receives a *sql.DB that should be passed to the ORM.
+// Capture setup failures and panics in the callback are reported by Assert.
 func (m *Migratiorm) Actual(fn func(db *sql.DB)) {
-	cap, err := capturer.New(m.normalizer)
+	m.actual, m.actualEffects, m.actualErr = m.capture(m.actualNormalizer, discardResult(fn), false)
+}
+
+// ExpectResult is like Expect, but the callback returns a value, e.g. the
+// rows the ORM loaded. When ActualResult is used as well, Assert reports
+// results that are not deeply equal (see reflect.DeepEqual).
+func (m *Migratiorm) ExpectResult(fn func(db *sql.DB) any) {
+	m.expected, m.expectEffects, m.expectErr = m.capture(m.normalizer, fn, true)
+	m.expectRan = true
+}
+
+// ActualResult is like Actual, but the callback returns a value that is
+// compared with the value returned to ExpectResult.
+func (m *Migratiorm) ActualResult(fn func(db *sql.DB) any) {
+	m.actual, m.actualEffects, m.actualErr = m.capture(m.actualNormalizer, fn, true)
+}
+
+// discardResult adapts a callback without a return value.
+func discardResult(fn func(db *sql.DB)) func(db *sql.DB) any {
+	return func(db *sql.DB) any {
+		fn(db)
+		return nil
+	}
+}
+
+// capture runs fn against a new capture database and returns the queries
+// captured and normalized with n, and the observable effects of fn.
+// A panic in fn is recovered and returned as an error along with the queries
+// captured before the panic.
+func (m *Migratiorm) capture(n *normalizer.Normalizer, fn func(db *sql.DB) any, hasResult bool) (queries []Query, fx effects, err error) {
+	cap, err := capturer.New(n, m.captureOptions(n))
 	if err != nil {
-		// Store error state - will be reported during Assert
-		return
+		return nil, fx, fmt.Errorf("failed to set up capture: %w", err)
 	}
 	defer cap.Close() //nolint:errcheck
 
-	fn(cap.DB())
+	var before map[string][]string
+	if tables := m.options.snapshotTables; len(tables) > 0 {
+		if cap.Direct() == nil {
+			return nil, fx, errors.New("failed to set up capture: table snapshots require WithDriver or WithConnector")
+		}
+		if before, err = snapshotTables(cap.Direct(), tables, m.options.snapshotIgnore); err != nil {
+			return nil, fx, fmt.Errorf("failed to snapshot tables: %w", err)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: debug.Stack()}
+		}
+		queries = buildQueries(n, cap.RawQueries())
+	}()
+
+	fx = effects{result: fn(cap.DB()), hasResult: hasResult}
+
+	if before != nil {
+		after, err := snapshotTables(cap.Direct(), m.options.snapshotTables, m.options.snapshotIgnore)
+		if err != nil {
+			return nil, fx, fmt.Errorf("failed to snapshot tables: %w", err)
+		}
+		fx.changes = diffTables(before, after)
+	}
 
-	m.actual = m.buildQueries(cap.RawQueries())
+	return nil, fx, nil
+}
+
+// panicError is a panic recovered from an Expect or Actual callback.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("callback panicked: %v\n\n%s", e.value, e.stack)
+}
+
+// captureOptions returns the options of a capturer whose queries are normalized with n.
+func (m *Migratiorm) captureOptions(n *normalizer.Normalizer) capturer.Options {
+	return capturer.Options{
+		Responses:          m.captureResponses(n),
+		RecordTransactions: m.options.transactions,
+		Connector:          m.options.connector,
+		Driver:             m.options.driver,
+		DSN:                m.options.dsn,
+	}
 }
 
 // buildQueries converts raw queries to Query objects with normalization.
-func (m *Migratiorm) buildQueries(rawQueries []capturer.RawQuery) []Query {
+func buildQueries(n *normalizer.Normalizer, rawQueries []capturer.RawQuery) []Query {
 	result := make([]Query, len(rawQueries))
 	for i, rq := range rawQueries {
-		normalized := m.normalizer.Normalize(rq.Query)
-		result[i] = Query{
-			Raw:        rq.Query,
-			Normalized: normalized,
-			Args:       rq.Args,
-			Operation:  detectOperation(rq.Query),
-		}
+		result[i] = buildQuery(n, rq.Query, rq.Args)
 	}
 	return result
 }
 
+// buildQuery converts a single raw query to a Query object with normalization.
+func buildQuery(n *normalizer.Normalizer, raw string, args []any) Query {
+	normalized, normalizedArgs := n.NormalizeArgs(raw, args)
+	return Query{
+		Raw:            raw,
+		Normalized:     normalized,
+		Args:           args,
+		NormalizedArgs: normalizedArgs,
+		Operation:      detectOperation(raw),
+		Statement:      n.Parse(normalized),
+	}
+}
+
 // Assert compares the expected and actual queries and fails the test if they don't match.
 func (m *Migratiorm) Assert(t testing.TB) {
 	t.Helper()
@@ -94,27 +180,135 @@ func (m *Migratiorm) AssertWithOptions(t testing.TB, opts ...AssertOption) {
 		opt(&assertOpts)
 	}
 
+	// A failed side makes the comparison meaningless, so only report the failure
+	if m.expectErr != nil || m.actualErr != nil {
+		if m.expectErr != nil {
+			t.Errorf("migratiorm: Expect failed: %v", m.expectErr)
+		}
+		if m.actualErr != nil {
+			t.Errorf("migratiorm: Actual failed: %v", m.actualErr)
+		}
+		return
+	}
+
 	// Determine comparison mode
-	comp := m.comparator
+	compOpts := comparator.Options{
+		Mode:           m.options.compareMode,
+		CompareArgs:    m.options.compareArgs && !assertOpts.ignoreArgs,
+		UnorderedLists: m.options.unorderedLists,
+		Structural:     m.options.structural,
+		Equivalences:   m.options.equivalences,
+	}
 	if assertOpts.ignoreOrder {
-		comp = comparator.New(comparator.CompareUnordered)
+		switch compOpts.Mode {
+		case comparator.CompareSubset, comparator.CompareSubsetUnordered:
+			compOpts.Mode = comparator.CompareSubsetUnordered
+		default:
+			compOpts.Mode = comparator.CompareUnordered
+		}
+	}
+	accepted, err := m.acceptedDifferences(assertOpts)
+	if err != nil {
+		t.Fatalf("migratiorm: %v", err)
 	}
+	compOpts.Accepted = accepted
+	comp := comparator.New(compOpts)
 
-	// Extract normalized queries for comparison
-	expectedNormalized := make([]string, len(m.expected))
-	for i, q := range m.expected {
-		expectedNormalized[i] = q.Normalized
+	expectedQueries, err := m.expectedForAssert()
+	if err != nil {
+		t.Fatalf("migratiorm: %v", err)
 	}
-	actualNormalized := make([]string, len(m.actual))
+
+	expected := comparisonQueries(expectedQueries)
+	countedExpected := make([]Query, 0, len(expectedQueries))
+	for i, q := range expectedQueries {
+		expected[i].Matchers = assertOpts.argMatchers(m.normalizer, i, q)
+		expected[i].Ignored = ignored(q, m.options.ignore, assertOpts.ignore)
+		if !expected[i].Ignored {
+			countedExpected = append(countedExpected, q)
+		}
+	}
+	actual := comparisonQueries(m.actual)
+	countedActual := make([]Query, 0, len(m.actual))
 	for i, q := range m.actual {
-		actualNormalized[i] = q.Normalized
+		actual[i].Ignored = ignored(q, m.options.ignore, assertOpts.ignore)
+		if !actual[i].Ignored {
+			countedActual = append(countedActual, q)
+		}
 	}
 
-	result := comp.Compare(expectedNormalized, actualNormalized)
+	result := comp.Compare(expected, actual)
 
+	// Query differences come first, followed by behavior and count differences
+	report := make([]string, 0)
 	if !result.Equal {
-		t.Error(comparator.FormatDifferences(result, len(m.expected), len(m.actual)))
+		if m.options.trace {
+			m.addTraces(result.Differences, expectedQueries, m.actual)
+		}
+		report = append(report, comparator.FormatDifferences(result, len(expectedQueries), len(m.actual), m.options.formatOptions(m.normalizer)))
+	}
+	if behavior := m.behaviorDifferences(); behavior != "" {
+		report = append(report, behavior)
 	}
+	if counts := m.countDifferences(countedExpected, countedActual, assertOpts); counts != "" {
+		report = append(report, counts)
+	}
+	if len(report) == 0 {
+		if accepted := comparator.FormatAccepted(result, m.options.formatOptions(m.normalizer)); accepted != "" {
+			t.Log(accepted)
+		}
+		if added := comparator.FormatAdded(result, m.options.formatOptions(m.normalizer)); added != "" {
+			t.Log(added)
+		}
+		return
+	}
+	if result.Equal {
+		report = append([]string{"migratiorm: assertion failed\n"}, report...)
+	}
+	t.Error(strings.Join(report, "\n"))
+}
+
+// expectedForAssert returns the expected queries to assert against.
+// In snapshot mode they are read from (or written to) the golden file.
+func (m *Migratiorm) expectedForAssert() ([]Query, error) {
+	path := m.options.goldenFile
+	if path == "" {
+		return m.expected, nil
+	}
+
+	if m.options.updatingGolden() {
+		// Without Expect there is nothing to record, and writing would
+		// replace the baseline with zero queries
+		if !m.expectRan {
+			return nil, fmt.Errorf("cannot update golden file %s: Expect was not called", path)
+		}
+		if err := writeGolden(path, m.expected); err != nil {
+			return nil, fmt.Errorf("failed to update golden file %s: %w", path, err)
+		}
+		return m.expected, nil
+	}
+
+	queries, err := readGolden(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden file %s (set %s=1 to create it): %w", path, GoldenUpdateEnv, err)
+	}
+	for i := range queries {
+		queries[i].Statement = m.normalizer.Parse(queries[i].Normalized)
+	}
+	return queries, nil
+}
+
+// comparisonQueries extracts normalized queries and arguments for comparison.
+func comparisonQueries(queries []Query) []comparator.Query {
+	result := make([]comparator.Query, len(queries))
+	for i, q := range queries {
+		result[i] = comparator.Query{
+			SQL:       q.Normalized,
+			Args:      q.NormalizedArgs,
+			Statement: q.Statement,
+		}
+	}
+	return result
 }
 
 // ExpectedQueries returns the captured expected queries for debugging.
@@ -1,8 +1,19 @@
 package migratiorm_test
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ucpr/migratiorm"
 )
@@ -87,6 +98,36 @@ func TestMigratiorm_NormalizesQuotes(t *testing.T) {
 	m.Assert(t)
 }
 
+func TestMigratiorm_WithDialect(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithDialect(migratiorm.DialectPostgreSQL))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE user_name = $1", "Alice")
+		db.Query(`SELECT * FROM "Orders" WHERE data ? 'paid'`)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query(`SELECT * FROM Users WHERE "user_name" = $1`, "Alice")
+		db.Query(`SELECT * FROM orders WHERE data ? 'paid'`)
+	})
+
+	expected := m.ExpectedQueries()
+	actual := m.ActualQueries()
+
+	if expected[0].Normalized != actual[0].Normalized {
+		t.Errorf("Expected unquoted identifiers to be case-insensitive, got %q and %q",
+			expected[0].Normalized, actual[0].Normalized)
+	}
+	if expected[1].Normalized == actual[1].Normalized {
+		t.Errorf("Expected quoted identifiers to be case-sensitive, got %q", actual[1].Normalized)
+	}
+	if got := expected[1].Normalized; got != `SELECT * FROM Orders WHERE data ? 'paid'` {
+		t.Errorf("Expected ? to be kept as an operator, got %q", got)
+	}
+}
+
 func TestMigratiorm_DetectsDifference(t *testing.T) {
 	t.Parallel()
 
@@ -175,6 +216,141 @@ func TestMigratiorm_StrictOrderFails(t *testing.T) {
 	}
 }
 
+func TestMigratiorm_StrictOrderAlignsInsertedQuery(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		db.Query("SELECT * FROM orders")
+		db.Query("SELECT * FROM items")
+		db.Query("SELECT * FROM tags")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		db.Query("SELECT * FROM audit_logs")
+		db.Query("SELECT * FROM orders")
+		db.Query("SELECT * FROM items")
+		db.Query("SELECT * FROM labels")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	output := rec.output()
+	for _, want := range []string{
+		"[0] OK: SELECT * FROM users",
+		"[1] EXTRA:\n      actual:   SELECT * FROM audit_logs",
+		"[2] OK: SELECT * FROM orders",
+		"[3] OK: SELECT * FROM items",
+		"[4] MODIFIED:\n      expected: SELECT * FROM tags\n      actual:   SELECT * FROM labels",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "MODIFIED") != 1 {
+		t.Errorf("Expected a single MODIFIED entry, got:\n%s", output)
+	}
+}
+
+func TestMigratiorm_ReportsTokenDiff(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithColor(false))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ? ORDER BY id LIMIT 10", 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age >= ? ORDER BY id", 18)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "      diff:     SELECT * FROM users WHERE age [->-] {+>=+} ? ORDER BY id [-LIMIT 10-]\n"
+	if !strings.Contains(rec.output(), want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+	}
+}
+
+func TestMigratiorm_ReportsTokenDiffWithinWords(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithColor(false))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE users.age>? AND COALESCE(users.deleted,0)=0", 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE users.age>=? AND COALESCE(users.archived,0)=0", 18)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "      diff:     SELECT * FROM users WHERE users.age[->-]{+>=+}? AND COALESCE(users.[-deleted-]{+archived+},0)=0\n"
+	if !strings.Contains(rec.output(), want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+	}
+}
+
+func TestMigratiorm_ReportsTokenDiffInColor(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithColor(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM orders WHERE age > ?", 18)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "FROM \x1b[31musers\x1b[0m \x1b[32morders\x1b[0m WHERE"
+	if !strings.Contains(rec.output(), want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+	}
+}
+
+func TestMigratiorm_WithPrettyPrint(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithPrettyPrint(true), migratiorm.WithColor(false))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users u LEFT JOIN orders o ON o.user_id = u.id WHERE u.id IN (SELECT id FROM admins WHERE active) ORDER BY u.id")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users u LEFT JOIN orders o ON o.user_id = u.id WHERE u.id IN (SELECT id FROM admins) ORDER BY u.id")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "      expected: SELECT *\n" +
+		"                FROM users u\n" +
+		"                LEFT JOIN orders o ON o.user_id = u.id\n" +
+		"                WHERE u.id IN (SELECT id FROM admins WHERE active)\n" +
+		"                ORDER BY u.id\n"
+	if !strings.Contains(rec.output(), want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+	}
+	if !strings.Contains(rec.output(), "                WHERE u.id IN (SELECT id FROM admins [-WHERE active-])\n") {
+		t.Errorf("Expected token diff split by clause, got:\n%s", rec.output())
+	}
+}
+
 func TestMigratiorm_ExpectedQueries(t *testing.T) {
 	t.Parallel()
 
@@ -227,6 +403,221 @@ func TestMigratiorm_WithUnorderedMode(t *testing.T) {
 	m.Assert(t)
 }
 
+func TestMigratiorm_WithSubsetMode(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithCompareMode(migratiorm.CompareSubset),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Exec("UPDATE users SET name = ? WHERE id = ?", "Alice", 1)
+	})
+
+	// Eager loading and an optimistic-lock check are allowed
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Query("SELECT * FROM orders WHERE user_id = ?", 1)
+		db.Query("SELECT version FROM users WHERE id = ?", 1)
+		db.Exec("UPDATE users SET name = ? WHERE id = ?", "Alice", 1)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_SubsetModePrefersEqualArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithCompareMode(migratiorm.CompareSubset),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	// The expected query is a subsequence despite the earlier lookup of id 2
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 2)
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Assert(t)
+
+	missing := migratiorm.New(
+		migratiorm.WithCompareMode(migratiorm.CompareSubset),
+	)
+	missing.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		db.Query("SELECT * FROM orders")
+	})
+	missing.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM accounts")
+		db.Query("SELECT * FROM orders")
+	})
+
+	rec := &recordingTB{TB: t}
+	missing.Assert(rec)
+
+	want := "Differences:\n" +
+		"  [0] MISSING:\n" +
+		"      expected: SELECT * FROM users\n" +
+		"  [1] ADDED: SELECT * FROM accounts\n" +
+		"  [2] OK: SELECT * FROM orders\n"
+	if !strings.Contains(rec.output(), want) {
+		t.Errorf("Expected unaligned queries to be reported as MISSING and ADDED, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_SubsetModeReportsAddedQueries(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithCompareMode(migratiorm.CompareSubset),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		db.Query("SELECT * FROM orders")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM orders")
+		db.Query("SELECT * FROM users")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "migratiorm: queries do not match\n\n" +
+		"Expected 2 queries, got 2 queries\n\n" +
+		"Differences:\n" +
+		"  [0] MISSING:\n" +
+		"      expected: SELECT * FROM users\n" +
+		"  [1] OK: SELECT * FROM orders\n" +
+		"  [2] ADDED: SELECT * FROM users\n"
+	if rec.output() != want {
+		t.Errorf("Unexpected output:\n%s", rec.output())
+	}
+
+	// Without order, every expected query is still issued
+	m.AssertWithOptions(t, migratiorm.IgnoreOrder())
+}
+
+func TestMigratiorm_SubsetModeLogsAddedQueries(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithCompareMode(migratiorm.CompareSubset),
+		migratiorm.WithColor(false),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	// Eager loading adds a query that the assertion allows
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Query("SELECT * FROM orders WHERE user_id = ?", 1)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if len(rec.errors) != 0 {
+		t.Fatalf("Expected added queries to pass, got:\n%s", rec.output())
+	}
+	want := "migratiorm: queries match with added queries\n\n" +
+		"  [1] ADDED: SELECT * FROM orders WHERE user_id = ?\n"
+	if len(rec.logs) != 1 || rec.logs[0] != want {
+		t.Errorf("Expected added queries to be logged, got %q", rec.logs)
+	}
+}
+
+func TestMigratiorm_DetectNPlusOne(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		db.Query("SELECT o.* FROM users u JOIN orders o ON o.user_id = u.id")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		for id := 1; id <= 3; id++ {
+			db.Query("SELECT * FROM orders WHERE user_id = ?", id)
+		}
+	})
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec, migratiorm.DetectNPlusOne())
+
+	want := "Query counts:\n  Actual issued 3 SELECTs on orders where Expect issued 1\n"
+	if !strings.HasSuffix(rec.output(), want) {
+		t.Errorf("Expected N+1 report, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_DetectNPlusOneIgnoresRepeatedArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		for i := 0; i < 3; i++ {
+			db.Query("SELECT * FROM users WHERE id = ?", 1)
+		}
+	})
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec, migratiorm.DetectNPlusOne())
+
+	if strings.Contains(rec.output(), "Query counts:") {
+		t.Errorf("Expected repeated identical queries not to be reported as N+1, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_QueryBudgets(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithCompareMode(migratiorm.CompareSubset))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Query("SELECT * FROM orders WHERE user_id = ?", 1)
+		db.Query("SELECT * FROM orders WHERE user_id = ?", 2)
+	})
+
+	m.AssertWithOptions(t, migratiorm.MaxQueries(3), migratiorm.MaxQueriesPerTable("users", 1))
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec,
+		migratiorm.MaxQueries(2),
+		migratiorm.MaxQueriesPerOperation(migratiorm.OperationSelect, 3),
+		migratiorm.MaxQueriesPerTable("ORDERS", 1),
+	)
+
+	want := "migratiorm: assertion failed\n\n" +
+		"Query counts:\n" +
+		"  Actual issued 3 queries, budget is 2 (Expect issued 1)\n" +
+		"  Actual issued 2 queries on ORDERS, budget is 1 (Expect issued 0)\n"
+	if rec.output() != want {
+		t.Errorf("Unexpected output:\n%s", rec.output())
+	}
+}
+
 func TestMigratiorm_QueryOperation(t *testing.T) {
 	t.Parallel()
 
@@ -430,6 +821,22 @@ func TestMigratiorm_SemanticComparisonAll(t *testing.T) {
 	m.Assert(t)
 }
 
+func TestMigratiorm_SemanticComparisonPredicateOrder(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithSemanticComparison(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE name = ? AND age > ?", "Alice", 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ? AND name = ?", 18, "Alice")
+	})
+
+	m.Assert(t)
+}
+
 func TestMigratiorm_SemanticComparisonReturningClause(t *testing.T) {
 	t.Parallel()
 
@@ -486,3 +893,1346 @@ func TestMigratiorm_SemanticComparisonTableQualifiersPreservedWithJoin(t *testin
 
 	m.Assert(t)
 }
+
+func TestMigratiorm_WithPreset(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithPreset(migratiorm.PresetSQLBoiler, migratiorm.PresetGORM),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query(`SELECT "users"."id", "users"."name" FROM "users" WHERE ("users"."id" = $1) AND ("users"."deleted_at" is null)`, 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM `users` WHERE `users`.`id` = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT ?", 1, 1)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_WithPresetFindByID(t *testing.T) {
+	t.Parallel()
+
+	// GORM's db.First(&user, id)
+	gormFirst := func(db *sql.DB) {
+		db.Query("SELECT * FROM `users` WHERE `users`.`id` = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1", 1)
+	}
+
+	for name, sqlboiler := range map[string]func(db *sql.DB){
+		// models.FindUser(ctx, db, id)
+		"FindUser": func(db *sql.DB) {
+			db.Query(`select * from "users" where "id"=$1 and "deleted_at" is null`, 1)
+		},
+		// models.Users(models.UserWhere.ID.EQ(id)).One(ctx, db)
+		"One": func(db *sql.DB) {
+			db.Query(`SELECT "users".* FROM "users" WHERE ("users"."id" = $1) AND ("users"."deleted_at" is null) LIMIT 1;`, 1)
+		},
+	} {
+		m := migratiorm.New(
+			migratiorm.WithPreset(migratiorm.PresetSQLBoiler, migratiorm.PresetGORM),
+		)
+		m.Expect(sqlboiler)
+		m.Actual(gormFirst)
+
+		rec := &recordingTB{TB: t}
+		m.Assert(rec)
+
+		if len(rec.errors) != 0 {
+			t.Errorf("Expected sqlboiler %s to match GORM First, got:\n%s", name, rec.output())
+		}
+	}
+}
+
+func TestMigratiorm_WithPresetReportsDroppedSoftDelete(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithPreset(migratiorm.PresetSQLBoiler, migratiorm.PresetGORM),
+		migratiorm.WithColor(false),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query(`SELECT * FROM "users" WHERE ("users"."deleted_at" is null) AND ("users"."age" > $1)`, 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM `users` WHERE `users`.`age` > ?", 18)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "      diff:     SELECT * FROM users WHERE age > ? [-AND deleted_at IS NULL-]\n"
+	if !strings.Contains(rec.output(), want) {
+		t.Errorf("Expected the dropped soft-delete filter to be reported, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_WithRewrite(t *testing.T) {
+	t.Parallel()
+
+	// The accounts table was renamed to users as part of the migration
+	m := migratiorm.New(
+		migratiorm.WithRewrite(migratiorm.StageLexical, migratiorm.ExpectedSide, func(q string) string {
+			return strings.ReplaceAll(q, "FROM accounts", "FROM users")
+		}),
+		migratiorm.WithRewrite(migratiorm.StageFinal, migratiorm.BothSides, func(q string) string {
+			return strings.TrimSuffix(q, " FOR UPDATE")
+		}),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM `accounts` WHERE id = $1 FOR UPDATE", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Assert(t)
+
+	if got := m.ActualQueries()[0].Normalized; got != "SELECT * FROM users WHERE id = ?" {
+		t.Errorf("Expected actual query to be unchanged, got %q", got)
+	}
+	if got := m.ExpectedQueries()[0].NormalizedArgs; len(got) != 1 || got[0] != int64(1) {
+		t.Errorf("Expected args to follow the rewritten placeholders, got %v", got)
+	}
+}
+
+func TestMigratiorm_WithTrace(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithTrace(true), migratiorm.WithColor(false))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("select * from `users` where id = $1", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? LIMIT 1", 1)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	for _, want := range []string{
+		"      expected trace:\n",
+		"        raw:                select * from `users` where id = $1\n",
+		"        RemoveComments:     (unchanged)\n",
+		"        RemoveQuotes:       select * from users where id = $1\n",
+		"        UnifyPlaceholders:  select * from users where id = ?\n",
+		"      actual trace:\n",
+		"        raw:                SELECT * FROM users WHERE id = ? LIMIT 1\n",
+	} {
+		if !strings.Contains(rec.output(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+		}
+	}
+
+	trace := m.Explain(migratiorm.ExpectedSide, "select 1")
+	if got := trace[len(trace)-1].Output; got != "SELECT 1" {
+		t.Errorf("Expected Explain to end with the normalized query, got %q", got)
+	}
+}
+
+func TestMigratiorm_WithParameterizeLiterals(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithParameterizeLiterals(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query(fmt.Sprintf("SELECT * FROM users WHERE age > %d AND name = '%s'", 18, "Alice"))
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ? AND name = ?", 18, "Alice")
+	})
+
+	m.Assert(t)
+
+	rec := &recordingTB{TB: t}
+	changed := migratiorm.New(migratiorm.WithParameterizeLiterals(true))
+	changed.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > 18")
+	})
+	changed.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 21)
+	})
+	changed.Assert(rec)
+
+	if !strings.Contains(rec.output(), "      expected args: [18]\n      actual args:   [21]\n") {
+		t.Errorf("Expected lifted literal to be compared as an argument, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_WithNormalizePagination(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithNormalizePagination(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users ORDER BY id LIMIT ?, ?", 20, 10)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users ORDER BY id OFFSET ? ROWS FETCH NEXT ? ROWS ONLY", 20, 10)
+	})
+
+	m.Assert(t)
+
+	if got := m.ActualQueries()[0].Normalized; got != "SELECT * FROM users ORDER BY id LIMIT ? OFFSET ?" {
+		t.Errorf("Expected pagination to be unified, got %q", got)
+	}
+}
+
+func TestMigratiorm_WithNormalizeUpserts(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithNormalizeUpserts(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name, age) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name), age = VALUES(age)", 1, "Alice", 30)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec(`INSERT INTO "users" ("id", "name", "age") VALUES ($1, $2, $3) ON CONFLICT DO UPDATE SET "age"="excluded"."age","name"="excluded"."name"`, 1, "Alice", 30)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_WithCollapseInLists(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithCollapseInLists(true), migratiorm.WithUnorderedInLists(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM orders WHERE user_id IN (?, ?, ?)", 1, 2, 3)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM orders WHERE user_id IN ($1, $2, $3)", 3, 1, 2)
+	})
+
+	m.Assert(t)
+
+	if got := m.ActualQueries()[0].Normalized; got != "SELECT * FROM orders WHERE user_id IN (?)" {
+		t.Errorf("Expected IN list to be collapsed, got %q", got)
+	}
+}
+
+func TestMigratiorm_CollapsedInListsGoldenFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "preload.golden")
+	query := func(db *sql.DB) {
+		db.Query("SELECT * FROM orders WHERE user_id IN (?, ?)", 1, "2")
+	}
+
+	update := migratiorm.New(migratiorm.WithCollapseInLists(true), migratiorm.WithGoldenFile(path), migratiorm.WithUpdateGolden(true))
+	update.Expect(query)
+	update.Actual(query)
+	update.Assert(t)
+
+	m := migratiorm.New(migratiorm.WithCollapseInLists(true), migratiorm.WithGoldenFile(path), migratiorm.WithUpdateGolden(false))
+	m.Actual(query)
+	m.Assert(t)
+}
+
+func TestMigratiorm_CollapsedInListsCompareArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithCollapseInLists(true))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM orders WHERE user_id IN (?, ?)", 1, 2)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM orders WHERE user_id IN (?, ?, ?)", 1, 2, 3)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if len(rec.errors) == 0 {
+		t.Fatal("Expected assertion to fail for different IN lists")
+	}
+	for _, want := range []string{
+		"  [0] ARGS: SELECT * FROM orders WHERE user_id IN (?)\n",
+		"      expected args: [[1, 2]]\n",
+		"      actual args:   [[1, 2, 3]]\n",
+	} {
+		if !strings.Contains(rec.output(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+		}
+	}
+}
+
+func TestMigratiorm_StructuralComparison(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithStructuralComparison(migratiorm.Equivalences{
+		UnorderedPredicates:  true,
+		UnorderedAssignments: true,
+	}))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE deleted_at IS NULL AND id = ?", 1)
+		db.Exec("UPDATE users SET name = ?, age = ? WHERE id = ?", "Alice", 30, 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? AND deleted_at IS NULL", 1)
+		db.Exec("UPDATE users SET age = ?, name = ? WHERE id = ?", 30, "Alice", 1)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_StructuralComparisonReportsPaths(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithStructuralComparison(migratiorm.Equivalences{
+		UnorderedPredicates: true,
+	}))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE deleted_at IS NULL AND id = ?", 1)
+		db.Query("SELECT * FROM users WHERE id IN (SELECT user_id FROM orders WHERE paid)")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Query("SELECT * FROM users WHERE id IN (SELECT user_id FROM orders WHERE NOT paid)")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	for _, want := range []string{
+		"- WHERE: predicate `deleted_at IS NULL` missing",
+		"- WHERE: predicate `id IN (SELECT …)`: subquery WHERE: predicate `paid` changed to `NOT paid`",
+	} {
+		if !strings.Contains(rec.output(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+		}
+	}
+}
+
+func TestMigratiorm_StructuralComparisonAlignsArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithStructuralComparison(migratiorm.Equivalences{
+		UnorderedInsertColumns: true,
+	}))
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (name, age) VALUES (?, ?)", "Alice", 30)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (age, name) VALUES (?, ?)", 30, "Bob")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if !strings.Contains(rec.output(), `actual args:   ["Bob", 30]`) {
+		t.Errorf("Expected actual args aligned with expected columns, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_WithResponse(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithResponse(migratiorm.Response{
+			Query:   "SELECT id, name FROM users WHERE id = ?",
+			Columns: []string{"id", "name"},
+			Rows:    [][]any{{1, "Alice"}},
+		}),
+	)
+
+	// Read a row, then issue a follow-up query that depends on it
+	readThenWrite := func(db *sql.DB) {
+		var id int64
+		var name string
+		if err := db.QueryRow("select id, name from users where id = $1", 1).Scan(&id, &name); err != nil {
+			return
+		}
+		db.Exec("UPDATE users SET name = ? WHERE id = ?", name+"!", id)
+	}
+
+	m.Expect(readThenWrite)
+	m.Actual(readThenWrite)
+
+	queries := m.ActualQueries()
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 queries, got %d", len(queries))
+	}
+	if queries[1].Args[0] != "Alice!" {
+		t.Errorf("Expected follow-up query to use scanned row, got args %v", queries[1].Args)
+	}
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_WithResponseMatchFunc(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithResponse(migratiorm.Response{
+			Match: func(q migratiorm.Query) bool {
+				return q.Operation == migratiorm.OperationSelect
+			},
+			Columns: []string{"count"},
+			Rows:    [][]any{{3}},
+		}),
+	)
+
+	var count int
+	m.Actual(func(db *sql.DB) {
+		db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	})
+
+	if count != 3 {
+		t.Errorf("Expected count 3, got %d", count)
+	}
+}
+
+func TestMigratiorm_WithDriver(t *testing.T) {
+	t.Parallel()
+
+	users := &usersDriver{names: make(map[int64]string)}
+	m := migratiorm.New(migratiorm.WithDriver(users, "users"))
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 1, "Alice")
+	})
+
+	// Actual reads the row written by Expect on a fresh connection
+	var name string
+	var scanErr, execErr error
+	m.Actual(func(db *sql.DB) {
+		scanErr = db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name)
+		_, execErr = db.Exec("DELETE FROM accounts WHERE id = ?", 1)
+	})
+
+	if scanErr != nil || name != "Alice" {
+		t.Errorf("Expected real row Alice, got %q (err: %v)", name, scanErr)
+	}
+	if execErr == nil || !strings.Contains(execErr.Error(), "no such table: accounts") {
+		t.Errorf("Expected real error to flow back, got %v", execErr)
+	}
+
+	if got := m.ExpectedQueries(); len(got) != 1 || got[0].Normalized != "INSERT INTO users (id, name) VALUES (?, ?)" {
+		t.Errorf("Expected INSERT to be recorded, got %v", got)
+	}
+	actual := m.ActualQueries()
+	if len(actual) != 2 {
+		t.Fatalf("Expected 2 queries, got %d", len(actual))
+	}
+	if actual[1].Normalized != "DELETE FROM accounts WHERE id = ?" {
+		t.Errorf("Expected failing statement to be recorded, got %q", actual[1].Normalized)
+	}
+}
+
+func TestMigratiorm_WithConnectorPrefersResponses(t *testing.T) {
+	t.Parallel()
+
+	users := &usersDriver{names: map[int64]string{1: "Alice"}}
+	m := migratiorm.New(
+		migratiorm.WithConnector(users),
+		migratiorm.WithResponse(migratiorm.Response{
+			Query:   "SELECT name FROM users WHERE id = ?",
+			Columns: []string{"name"},
+			Rows:    [][]any{{"Bob"}},
+		}),
+	)
+
+	var name string
+	m.Actual(func(db *sql.DB) {
+		db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name)
+	})
+
+	if name != "Bob" {
+		t.Errorf("Expected canned row Bob, got %q", name)
+	}
+	if len(m.ActualQueries()) != 1 {
+		t.Errorf("Expected 1 query, got %d", len(m.ActualQueries()))
+	}
+}
+
+func TestMigratiorm_ComparesResults(t *testing.T) {
+	t.Parallel()
+
+	users := &usersDriver{names: map[int64]string{1: "Alice"}}
+	m := migratiorm.New(migratiorm.WithDriver(users, "users"))
+
+	m.ExpectResult(func(db *sql.DB) any {
+		var name string
+		db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name)
+		return name
+	})
+	m.ActualResult(func(db *sql.DB) any {
+		var name string
+		db.QueryRow("SELECT name FROM users WHERE id = ?", 2).Scan(&name)
+		return name
+	})
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec, migratiorm.IgnoreArgs())
+
+	want := "migratiorm: assertion failed\n\n" +
+		"Behavior differences:\n" +
+		"  RESULT:\n" +
+		"      expected: Alice\n" +
+		"      actual:   \n"
+	if rec.output() != want {
+		t.Errorf("Expected result difference, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_WithSnapshotTables(t *testing.T) {
+	t.Parallel()
+
+	users := &usersDriver{names: make(map[int64]string)}
+	m := migratiorm.New(
+		migratiorm.WithDriver(users, "users"),
+		migratiorm.WithSnapshotTables("users"),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 1, "Alice")
+	})
+	m.Actual(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 2, "Alice")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	for _, want := range []string{
+		"[0] ARGS: INSERT INTO users (id, name) VALUES (?, ?)",
+		"Behavior differences:\n  TABLE users:\n" +
+			"      inserted only by Expect: (id=1, name=Alice)\n" +
+			"      inserted only by Actual: (id=2, name=Alice)\n",
+	} {
+		if !strings.Contains(rec.output(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, rec.output())
+		}
+	}
+}
+
+func TestMigratiorm_WithSnapshotIgnoreColumns(t *testing.T) {
+	t.Parallel()
+
+	// Actual runs after Expect, so the database assigns it the next id
+	users := &usersDriver{names: make(map[int64]string)}
+	m := migratiorm.New(
+		migratiorm.WithDriver(users, "users"),
+		migratiorm.WithSnapshotTables("users"),
+		migratiorm.WithSnapshotIgnoreColumns("users.id"),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 1, "Alice")
+	})
+	m.Actual(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 2, "Alice")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec, migratiorm.IgnoreArgs())
+
+	if len(rec.errors) != 0 {
+		t.Errorf("Expected rows without the ignored id to match, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_WithSnapshotTablesRequiresDriver(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithSnapshotTables("users"))
+	m.Expect(func(db *sql.DB) {
+		t.Error("Expected callback not to run without a real database")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if !strings.Contains(rec.output(), "table snapshots require WithDriver or WithConnector") {
+		t.Errorf("Expected setup failure to be reported, got:\n%s", rec.output())
+	}
+}
+
+// usersDriver is an in-memory database with a single users table.
+// It implements only the minimal driver interfaces, so statements are prepared.
+type usersDriver struct {
+	mu    sync.Mutex
+	names map[int64]string
+}
+
+func (d *usersDriver) Open(name string) (driver.Conn, error) {
+	return &usersConn{d: d}, nil
+}
+
+func (d *usersDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return &usersConn{d: d}, nil
+}
+
+func (d *usersDriver) Driver() driver.Driver {
+	return d
+}
+
+type usersConn struct {
+	d *usersDriver
+}
+
+func (c *usersConn) Prepare(query string) (driver.Stmt, error) {
+	return &usersStmt{d: c.d, query: query}, nil
+}
+
+// CheckNamedValue accepts userKey unconverted, like drivers accepting their
+// own argument types, and converts everything else by default.
+func (c *usersConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(userKey); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *usersConn) Close() error {
+	return nil
+}
+
+func (c *usersConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type usersStmt struct {
+	d     *usersDriver
+	query string
+}
+
+func (s *usersStmt) Close() error {
+	return nil
+}
+
+func (s *usersStmt) NumInput() int {
+	return -1
+}
+
+func (s *usersStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !strings.HasPrefix(s.query, "INSERT INTO users") {
+		return nil, errors.New("no such table: accounts")
+	}
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.names[args[0].(int64)] = args[1].(string)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *usersStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	if s.query == "SELECT * FROM users" {
+		rows := &usersRows{columns: []string{"id", "name"}}
+		for id, name := range s.d.names {
+			rows.values = append(rows.values, []driver.Value{id, name})
+		}
+		return rows, nil
+	}
+
+	id, ok := args[0].(int64)
+	if key, isKey := args[0].(userKey); isKey {
+		id, ok = key.id, true
+	}
+	rows := &usersRows{columns: []string{"name"}}
+	if name, found := s.d.names[id]; ok && found {
+		rows.values = append(rows.values, []driver.Value{name})
+	}
+	return rows, nil
+}
+
+// userKey is a driver-specific argument type of usersDriver.
+type userKey struct {
+	id int64
+}
+
+type usersRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *usersRows) Columns() []string {
+	return r.columns
+}
+
+func (r *usersRows) Close() error {
+	return nil
+}
+
+func (r *usersRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestMigratiorm_AcceptDifference(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM `users` WHERE `users`.`id` = ? ORDER BY `users`.`id` LIMIT ?", 1, 1)
+	})
+
+	accept := migratiorm.AcceptDifference(
+		"SELECT * FROM users WHERE id = ?",
+		"SELECT * FROM users WHERE users.id = ? ORDER BY users.id LIMIT ?",
+		"GORM First() orders by primary key",
+	)
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec, accept)
+
+	if len(rec.errors) != 0 {
+		t.Fatalf("Expected accepted difference to pass, got:\n%s", rec.output())
+	}
+	want := "migratiorm: queries match with accepted differences\n\n" +
+		"  [0] ACCEPTED: GORM First() orders by primary key\n" +
+		"      expected: SELECT * FROM users WHERE id = ?\n" +
+		"      actual:   SELECT * FROM users WHERE users.id = ? ORDER BY users.id LIMIT ?\n"
+	if strings.Join(rec.logs, "\n") != want {
+		t.Errorf("Unexpected log:\n%s", strings.Join(rec.logs, "\n"))
+	}
+
+	// A changed difference fails again
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE users.id = ? ORDER BY users.id DESC LIMIT ?", 1, 1)
+	})
+
+	rec = &recordingTB{TB: t}
+	m.AssertWithOptions(rec, accept)
+
+	if !strings.Contains(rec.output(), "[0] MODIFIED:") {
+		t.Errorf("Expected changed difference to fail, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_AcceptDifferencesFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "accepted.json")
+	content := `{"accepted": [{"expected": "", "actual": "SELECT VERSION()", "reason": "GORM checks the server version"}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT VERSION()")
+		db.Query("SELECT * FROM users")
+	})
+
+	m.AssertWithOptions(t, migratiorm.AcceptDifferencesFile(path))
+}
+
+// recordingTB records assertion failures and logs instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+	logs   []string
+}
+
+func (r *recordingTB) Log(args ...any) {
+	r.logs = append(r.logs, fmt.Sprint(args...))
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Error(args ...any) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// Fatalf records the failure and stops the calling goroutine, so callers
+// run the code under test in their own goroutine (see runRecording).
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	runtime.Goexit()
+}
+
+// runRecording runs fn in a separate goroutine so that Fatalf does not stop the test.
+func runRecording(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+}
+
+func (r *recordingTB) output() string {
+	return strings.Join(r.errors, "\n")
+}
+
+func TestMigratiorm_DetectsArgsDifference(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("UPDATE users SET age = ? WHERE id = ?", 30, 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("UPDATE users SET age = ? WHERE id = ?", 31, 1)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if !strings.Contains(rec.output(), "[0] ARGS: UPDATE users SET age = ? WHERE id = ?") {
+		t.Errorf("Expected ARGS difference, got:\n%s", rec.output())
+	}
+	if !strings.Contains(rec.output(), "expected args: [30, 1]") || !strings.Contains(rec.output(), "actual args:   [31, 1]") {
+		t.Errorf("Expected both argument lists in output, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_PairsRepeatedQueriesByArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithColor(false))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Query("SELECT * FROM users WHERE id = ?", 2)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 2)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	want := "Differences:\n" +
+		"  [0] MISSING:\n" +
+		"      expected: SELECT * FROM users WHERE id = ?\n" +
+		"  [1] OK: SELECT * FROM users WHERE id = ?\n"
+	if !strings.Contains(rec.output(), want) || strings.Contains(rec.output(), "ARGS") {
+		t.Errorf("Expected only the lookup of id 1 to be missing, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_CoercesEquivalentArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? AND name = ?", int32(1), []byte("Alice"))
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? AND name = ?", int64(1), "Alice")
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_IgnoreArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 20)
+	})
+
+	m.AssertWithOptions(t, migratiorm.IgnoreArgs())
+}
+
+func TestMigratiorm_WithIgnoreQuery(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(migratiorm.WithIgnoreQuery(`^SELECT VERSION\(\)$`))
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT VERSION()")
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_IgnoreOperationShowsIgnoredQueries(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("SET NAMES utf8mb4")
+		db.Query("SELECT * FROM users WHERE id = ?", 1)
+		db.Exec("INSERT INTO audit_log (action) VALUES (?)", "read")
+		db.Exec("DELETE FROM sessions")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec,
+		migratiorm.IgnoreOperation(migratiorm.OperationOther),
+		migratiorm.IgnoreFunc(func(q migratiorm.Query) bool {
+			return strings.Contains(q.Normalized, "audit_log")
+		}),
+	)
+
+	want := "migratiorm: queries do not match\n\n" +
+		"Expected 1 queries, got 4 queries\n\n" +
+		"Differences:\n" +
+		"  [0] IGNORED (actual): SET NAMES utf8mb4\n" +
+		"  [1] OK: SELECT * FROM users WHERE id = ?\n" +
+		"  [2] IGNORED (actual): INSERT INTO audit_log (action) VALUES (?)\n" +
+		"  [3] EXTRA:\n" +
+		"      actual:   DELETE FROM sessions\n"
+	if rec.output() != want {
+		t.Errorf("Unexpected output:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_WithCompareArgsDisabled(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithCompareArgs(false),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 18)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 20)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_NormalizedArgsFollowPlaceholders(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE name = $2 AND age > $1", 18, "Alice")
+	})
+
+	queries := m.ExpectedQueries()
+	if got := fmt.Sprint(queries[0].NormalizedArgs); got != "[Alice 18]" {
+		t.Errorf("Expected normalized args [Alice 18], got %s", got)
+	}
+	if got := fmt.Sprint(queries[0].Args); got != "[18 Alice]" {
+		t.Errorf("Expected raw args [18 Alice], got %s", got)
+	}
+}
+
+func TestMigratiorm_MatchColumn(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name, created_at) VALUES (?, ?, ?)",
+			"0b6a4e1c-7d7e-4f0e-9a59-6f1c1f6c2a01", "Alice", time.Now())
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (id, name, created_at) VALUES (?, ?, ?)",
+			"5f2d9c3e-1a4b-4c8d-8e7f-0a1b2c3d4e5f", "Alice", time.Now().Add(time.Second))
+	})
+
+	m.AssertWithOptions(t,
+		migratiorm.MatchColumn("id", migratiorm.MatchRegexp(`^[0-9a-f-]{36}$`)),
+		migratiorm.MatchColumn("created_at", migratiorm.TimeWithin(time.Minute)),
+	)
+}
+
+func TestMigratiorm_MatchArg(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 18)
+		db.Query("SELECT * FROM orders WHERE token = ? AND user_id = ?", "abc", 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE age > ?", 18)
+		db.Query("SELECT * FROM orders WHERE token = ? AND user_id = ?", "xyz", 1)
+	})
+
+	m.AssertWithOptions(t, migratiorm.MatchArg(1, 0, migratiorm.AnyArgOfType("")))
+}
+
+func TestMigratiorm_MatcherStillChecksOtherArgs(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Exec("UPDATE users SET updated_at = ? WHERE id = ?", time.Now(), 1)
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("UPDATE users SET updated_at = ? WHERE id = ?", time.Now(), 2)
+	})
+
+	rec := &recordingTB{TB: t}
+	m.AssertWithOptions(rec, migratiorm.MatchColumn("updated_at", migratiorm.AnyArg()))
+
+	if !strings.Contains(rec.output(), "ARGS") || !strings.Contains(rec.output(), "<any>") {
+		t.Errorf("Expected ARGS difference describing the matcher, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_CapturesTransactions(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true})
+		if err != nil {
+			return
+		}
+		tx.Query("SELECT * FROM users")
+		tx.Commit()
+
+		tx, err = db.Begin()
+		if err != nil {
+			return
+		}
+		tx.Exec("DELETE FROM users WHERE id = ?", 1)
+		tx.Rollback()
+	})
+
+	queries := m.ExpectedQueries()
+	if len(queries) != 6 {
+		t.Fatalf("Expected 6 queries, got %d", len(queries))
+	}
+
+	expected := []struct {
+		normalized string
+		operation  migratiorm.OperationType
+	}{
+		{"BEGIN ISOLATION LEVEL SERIALIZABLE READ ONLY", migratiorm.OperationBegin},
+		{"SELECT * FROM users", migratiorm.OperationSelect},
+		{"COMMIT", migratiorm.OperationCommit},
+		{"BEGIN", migratiorm.OperationBegin},
+		{"DELETE FROM users WHERE id = ?", migratiorm.OperationDelete},
+		{"ROLLBACK", migratiorm.OperationRollback},
+	}
+	for i, e := range expected {
+		if queries[i].Normalized != e.normalized {
+			t.Errorf("[%d] Expected normalized query %q, got %q", i, e.normalized, queries[i].Normalized)
+		}
+		if queries[i].Operation != e.operation {
+			t.Errorf("[%d] Expected %v operation, got %v", i, e.operation, queries[i].Operation)
+		}
+	}
+}
+
+func TestMigratiorm_DetectsDroppedTransaction(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			return
+		}
+		tx.Exec("INSERT INTO users (name) VALUES (?)", "Alice")
+		tx.Exec("INSERT INTO users (name) VALUES (?)", "Bob")
+		tx.Commit()
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("INSERT INTO users (name) VALUES (?)", "Alice")
+		db.Exec("INSERT INTO users (name) VALUES (?)", "Bob")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if !strings.Contains(rec.output(), "BEGIN") || !strings.Contains(rec.output(), "COMMIT") {
+		t.Errorf("Expected transaction boundaries in differences, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_FailedBeginIsNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	// The users driver does not support transactions, so Begin fails
+	m := migratiorm.New(migratiorm.WithDriver(&usersDriver{names: make(map[int64]string)}, "users"))
+
+	m.Expect(func(db *sql.DB) {
+		if _, err := db.Begin(); err == nil {
+			t.Error("Expected Begin to fail")
+		}
+		db.Query("SELECT name FROM users WHERE id = ?", 1)
+	})
+
+	queries := m.ExpectedQueries()
+	if len(queries) != 1 || queries[0].Normalized != "SELECT name FROM users WHERE id = ?" {
+		t.Errorf("Expected only the SELECT to be recorded, got %+v", queries)
+	}
+}
+
+func TestMigratiorm_WithCaptureTransactionsDisabled(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithCaptureTransactions(false),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			return
+		}
+		tx.Exec("DELETE FROM users WHERE id = ?", 1)
+		tx.Commit()
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Exec("DELETE FROM users WHERE id = ?", 1)
+	})
+
+	m.Assert(t)
+}
+
+func TestMigratiorm_GoldenFileUpdateWithoutExpect(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "find_user.golden")
+	baseline := []byte(`{"queries":[{"operation":"SELECT","query":"SELECT * FROM users"}]}` + "\n")
+	if err := os.WriteFile(path, baseline, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The legacy implementation is gone, so Expect is never called
+	m := migratiorm.New(migratiorm.WithGoldenFile(path), migratiorm.WithUpdateGolden(true))
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+	})
+
+	rec := &recordingTB{TB: t}
+	runRecording(func() { m.Assert(rec) })
+
+	if !strings.Contains(rec.output(), "Expect was not called") {
+		t.Errorf("Expected update without Expect to fail, got:\n%s", rec.output())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(baseline) {
+		t.Errorf("Expected golden file to be kept, got:\n%s", data)
+	}
+}
+
+func TestMigratiorm_GoldenFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "testdata", "find_user.golden")
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Record the golden file from the legacy implementation
+	update := migratiorm.New(
+		migratiorm.WithGoldenFile(path),
+		migratiorm.WithUpdateGolden(true),
+	)
+	update.Expect(func(db *sql.DB) {
+		db.Query("select * from users where id = $1 and name = $2", 1, "Alice")
+		db.Exec("UPDATE users SET avatar = ?, score = ?, created_at = ? WHERE deleted_at IS ?", []byte("png"), 1.5, createdAt, nil)
+	})
+	update.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? AND name = ?", 1, "Alice")
+		db.Exec("UPDATE users SET avatar = ?, score = ?, created_at = ? WHERE deleted_at IS ?", []byte("png"), 1.5, createdAt, nil)
+	})
+	update.Assert(t)
+
+	// Assert against the golden file without the legacy implementation
+	m := migratiorm.New(
+		migratiorm.WithGoldenFile(path),
+		migratiorm.WithUpdateGolden(false),
+	)
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? AND name = ?", 1, "Alice")
+		db.Exec("UPDATE users SET avatar = ?, score = ?, created_at = ? WHERE deleted_at IS ?", "png", 1.5, createdAt, nil)
+	})
+	m.Assert(t)
+
+	// Differences from the golden file are reported
+	changed := migratiorm.New(
+		migratiorm.WithGoldenFile(path),
+		migratiorm.WithUpdateGolden(false),
+	)
+	changed.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users WHERE id = ? AND name = ?", 2, "Alice")
+	})
+
+	rec := &recordingTB{TB: t}
+	changed.Assert(rec)
+
+	if !strings.Contains(rec.output(), "ARGS") || !strings.Contains(rec.output(), "MISSING") {
+		t.Errorf("Expected ARGS and MISSING differences, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_GoldenFileWithDriverArgs(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "find_user.golden")
+	users := &usersDriver{names: map[int64]string{1: "Alice"}}
+
+	// The real driver accepts userKey unconverted, so it reaches the capture as is
+	update := migratiorm.New(
+		migratiorm.WithDriver(users, "users"),
+		migratiorm.WithGoldenFile(path),
+		migratiorm.WithUpdateGolden(true),
+	)
+	update.Expect(func(db *sql.DB) {
+		db.Query("SELECT name FROM users WHERE id = ?", userKey{id: 1})
+	})
+	update.Actual(func(db *sql.DB) {
+		db.Query("SELECT name FROM users WHERE id = ?", userKey{id: 1})
+	})
+	update.Assert(t)
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(golden), `"type": "migratiorm_test.userKey"`) {
+		t.Errorf("Expected the argument to be stored with its Go type, got:\n%s", golden)
+	}
+
+	m := migratiorm.New(
+		migratiorm.WithDriver(users, "users"),
+		migratiorm.WithGoldenFile(path),
+		migratiorm.WithUpdateGolden(false),
+	)
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT name FROM users WHERE id = ?", userKey{id: 1})
+	})
+	m.Assert(t)
+
+	changed := migratiorm.New(
+		migratiorm.WithDriver(users, "users"),
+		migratiorm.WithGoldenFile(path),
+		migratiorm.WithUpdateGolden(false),
+	)
+	changed.Actual(func(db *sql.DB) {
+		db.Query("SELECT name FROM users WHERE id = ?", userKey{id: 2})
+	})
+
+	rec := &recordingTB{TB: t}
+	changed.Assert(rec)
+
+	if !strings.Contains(rec.output(), "[0] ARGS: SELECT name FROM users WHERE id = ?") {
+		t.Errorf("Expected ARGS difference, got:\n%s", rec.output())
+	}
+}
+
+func TestMigratiorm_ReportsCallbackPanic(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New()
+
+	m.Expect(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+	})
+
+	m.Actual(func(db *sql.DB) {
+		db.Query("SELECT * FROM users")
+		panic("repository exploded")
+	})
+
+	// Queries captured before the panic are kept
+	if len(m.ActualQueries()) != 1 {
+		t.Errorf("Expected 1 actual query, got %d", len(m.ActualQueries()))
+	}
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	out := rec.output()
+	if !strings.Contains(out, "Actual failed: callback panicked: repository exploded") {
+		t.Errorf("Expected panic to be reported, got:\n%s", out)
+	}
+	if !strings.Contains(out, "goroutine") {
+		t.Errorf("Expected stack trace in report, got:\n%s", out)
+	}
+	if strings.Contains(out, "Expect failed") {
+		t.Errorf("Expected only Actual to be reported, got:\n%s", out)
+	}
+}
+
+func TestMigratiorm_ReportsCaptureSetupFailure(t *testing.T) {
+	t.Parallel()
+
+	m := migratiorm.New(
+		migratiorm.WithResponse(migratiorm.Response{
+			Query:   "SELECT id FROM users",
+			Columns: []string{"id"},
+			Rows:    [][]any{{1, "unexpected"}},
+		}),
+	)
+
+	m.Expect(func(db *sql.DB) {
+		t.Error("Expected callback not to run when capture setup fails")
+	})
+
+	rec := &recordingTB{TB: t}
+	m.Assert(rec)
+
+	if !strings.Contains(rec.output(), "Expect failed: failed to set up capture") {
+		t.Errorf("Expected setup failure to be reported, got:\n%s", rec.output())
+	}
+}
@@ -1,6 +1,10 @@
 package migratiorm
 
 import (
+	"database/sql/driver"
+	"os"
+
+	"github.com/ucpr/migratiorm/internal/ast"
 	"github.com/ucpr/migratiorm/internal/comparator"
 	"github.com/ucpr/migratiorm/internal/normalizer"
 )
@@ -10,8 +14,22 @@ type CompareMode = comparator.CompareMode
 
 // Comparison mode constants.
 const (
-	CompareStrict    = comparator.CompareStrict
-	CompareUnordered = comparator.CompareUnordered
+	CompareStrict          = comparator.CompareStrict
+	CompareUnordered       = comparator.CompareUnordered
+	CompareSubset          = comparator.CompareSubset
+	CompareSubsetUnordered = comparator.CompareSubsetUnordered
+)
+
+// Dialect identifies the SQL dialect of the captured queries.
+type Dialect = normalizer.Dialect
+
+// Dialect constants.
+const (
+	DialectGeneric    = normalizer.DialectGeneric
+	DialectMySQL      = normalizer.DialectMySQL
+	DialectPostgreSQL = normalizer.DialectPostgreSQL
+	DialectSQLite     = normalizer.DialectSQLite
+	DialectSQLServer  = normalizer.DialectSQLServer
 )
 
 // Option configures a Migratiorm instance.
@@ -20,24 +38,66 @@ type Option func(*options)
 // options holds the configuration for Migratiorm.
 type options struct {
 	compareMode       comparator.CompareMode
+	compareArgs       bool
+	transactions      bool
+	goldenFile        string
+	updateGolden      *bool
 	normalizerOptions normalizer.Options
+	responses         []Response
+	connector         driver.Connector
+	driver            driver.Driver
+	dsn               string
+	snapshotTables    []string
+	snapshotIgnore    []string
+	ignore            []ignoreRule
+	rewrites          []rewriteRule
+	structural        bool
+	equivalences      Equivalences
+	color             *bool
+	pretty            bool
+	trace             bool
+	unorderedLists    bool
 }
 
 // defaultOptions returns the default options.
 func defaultOptions() options {
 	return options{
 		compareMode:       comparator.CompareStrict,
+		compareArgs:       true,
+		transactions:      true,
 		normalizerOptions: normalizer.DefaultOptions(),
 	}
 }
 
 // WithCompareMode sets the comparison mode.
+// In the subset modes, additional Actual queries do not fail the assertion and
+// are logged as ADDED when it passes.
 func WithCompareMode(mode CompareMode) Option {
 	return func(o *options) {
 		o.compareMode = mode
 	}
 }
 
+// WithCompareArgs enables or disables bind argument comparison.
+// When enabled (the default), queries with equal SQL but different bind
+// arguments are reported as ARGS differences. Equivalent driver values
+// such as int and int64 or []byte and string are treated as equal.
+func WithCompareArgs(enabled bool) Option {
+	return func(o *options) {
+		o.compareArgs = enabled
+	}
+}
+
+// WithCaptureTransactions enables or disables capturing transaction boundaries.
+// When enabled (the default), BEGIN (including isolation level and read-only
+// options), COMMIT and ROLLBACK are recorded as queries and compared like any
+// other query, so added or dropped transactions are reported.
+func WithCaptureTransactions(enabled bool) Option {
+	return func(o *options) {
+		o.transactions = enabled
+	}
+}
+
 // WithUnifyPlaceholders enables or disables placeholder unification.
 func WithUnifyPlaceholders(enabled bool) Option {
 	return func(o *options) {
@@ -66,6 +126,86 @@ func WithRemoveQuotes(enabled bool) Option {
 	}
 }
 
+// WithSortPredicates enables or disables sorting of AND-ed and OR-ed predicates
+// in WHERE, HAVING and JOIN ... ON conditions, at every nesting level.
+// Bind arguments are reordered along with their predicates, so that
+// "WHERE a = ? AND b = ?" and "WHERE b = ? AND a = ?" compare equal
+// including their arguments.
+func WithSortPredicates(enabled bool) Option {
+	return func(o *options) {
+		o.normalizerOptions.SortPredicates = enabled
+	}
+}
+
+// WithCollapseInLists enables or disables collapsing IN lists of placeholders,
+// so that batches of different sizes, as produced by eager loading in GORM
+// (Preload) or sqlboiler (Load), compare equal:
+// "WHERE id IN (?, ?, ?)" becomes "WHERE id IN (?)". The bind arguments of the
+// list are grouped into a single []any argument, which is compared element by
+// element (see WithUnorderedInLists).
+func WithCollapseInLists(enabled bool) Option {
+	return func(o *options) {
+		o.normalizerOptions.CollapseInLists = enabled
+	}
+}
+
+// WithParameterizeLiterals enables or disables replacing inline numeric,
+// string and boolean literals with placeholders, so that queries built with
+// interpolated values compare equal to queries binding the same values:
+// "WHERE age > 18" becomes "WHERE age > ?" with 18 added to the bind arguments
+// at the position of the placeholder.
+func WithParameterizeLiterals(enabled bool) Option {
+	return func(o *options) {
+		o.normalizerOptions.ParameterizeLiterals = enabled
+	}
+}
+
+// WithNormalizePagination enables or disables rewriting pagination clauses to
+// LIMIT count OFFSET skip, so that MySQL's "LIMIT 20, 10", "OFFSET 20 ROWS
+// FETCH NEXT 10 ROWS ONLY" and SQL Server's "SELECT TOP 10" compare equal to
+// the LIMIT/OFFSET form. Bind arguments of the row counts are reordered with
+// their clauses.
+func WithNormalizePagination(enabled bool) Option {
+	return func(o *options) {
+		o.normalizerOptions.NormalizePagination = enabled
+	}
+}
+
+// WithNormalizeUpserts enables or disables rewriting upserts to
+// "INSERT ... ON CONFLICT [(target)] DO UPDATE SET ..." or "... DO NOTHING",
+// so that ON DUPLICATE KEY UPDATE, INSERT IGNORE, INSERT OR REPLACE and
+// ON CONFLICT compare equal. Conflict target columns and update assignments
+// are sorted, and VALUES(col), excluded.col and MySQL row alias references
+// are written as EXCLUDED.col. MySQL upserts do not name a conflict target,
+// so they only match ON CONFLICT clauses without one.
+func WithNormalizeUpserts(enabled bool) Option {
+	return func(o *options) {
+		o.normalizerOptions.NormalizeUpserts = enabled
+	}
+}
+
+// WithUnorderedInLists enables or disables comparing the arguments of
+// collapsed IN lists as sets, ignoring the order of their elements.
+// It has no effect without WithCollapseInLists.
+func WithUnorderedInLists(enabled bool) Option {
+	return func(o *options) {
+		o.unorderedLists = enabled
+	}
+}
+
+// WithDialect sets the SQL dialect used to tokenize queries.
+// The dialect decides which quote characters delimit identifiers and strings,
+// which placeholder syntaxes are recognized, whether unquoted identifiers are
+// case-insensitive, and which dialect keywords are uppercased. For example,
+// with DialectMySQL "x" is a string literal and @x is a user variable, and
+// with DialectPostgreSQL unquoted identifiers are folded to lower case.
+// The default DialectGeneric accepts the syntax of all supported databases.
+func WithDialect(dialect Dialect) Option {
+	return func(o *options) {
+		o.normalizerOptions.Dialect = dialect
+	}
+}
+
 // WithSemanticComparison enables semantic comparison mode.
 // When enabled, the following normalizations are applied:
 //   - SELECT column lists are normalized to * (SELECT id, name → SELECT *)
@@ -73,6 +213,7 @@ func WithRemoveQuotes(enabled bool) Option {
 //   - Redundant ASC in ORDER BY is removed (ORDER BY x ASC → ORDER BY x)
 //   - INSERT column order is sorted alphabetically
 //   - UPDATE SET column order is sorted alphabetically
+//   - AND/OR predicates are sorted (WHERE b = ? AND a = ? → WHERE a = ? AND b = ?)
 //   - RETURNING clause is removed (INSERT ... RETURNING id → INSERT ...)
 //   - Table qualifiers are removed in simple queries (users.age → age)
 //     Note: Queries with JOINs or subqueries are not modified to avoid ambiguity.
@@ -83,23 +224,90 @@ func WithSemanticComparison(enabled bool) Option {
 		o.normalizerOptions.NormalizeOrderByAsc = enabled
 		o.normalizerOptions.SortInsertColumns = enabled
 		o.normalizerOptions.SortUpdateColumns = enabled
+		o.normalizerOptions.SortPredicates = enabled
 		o.normalizerOptions.RemoveReturningClause = enabled
 		o.normalizerOptions.NormalizeTableQualifiers = enabled
 	}
 }
 
+// Equivalences relaxes structural comparison. The zero value requires every
+// clause item to match in order.
+type Equivalences = ast.Equivalences
+
+// WithStructuralComparison enables structural comparison.
+// Queries are parsed into statement trees (see Query.Statement) and compared
+// clause by clause instead of as normalized strings, with the given
+// equivalences applied. Differences are reported as paths into the tree, e.g.
+// "WHERE: predicate `deleted_at IS NULL` missing". Bind arguments are compared
+// by their position in the tree, so equivalent reorderings keep them aligned.
+// Queries that cannot be parsed are compared as normalized strings.
+func WithStructuralComparison(equivalences Equivalences) Option {
+	return func(o *options) {
+		o.structural = true
+		o.equivalences = equivalences
+	}
+}
+
+// WithColor forces ANSI color highlighting of changed tokens in MODIFIED
+// differences on or off. By default colors are used when standard output is a
+// terminal and the NO_COLOR environment variable is not set; otherwise changes
+// are marked as [-removed-] and {+inserted+}.
+func WithColor(enabled bool) Option {
+	return func(o *options) {
+		o.color = &enabled
+	}
+}
+
+// WithPrettyPrint enables or disables printing queries in failure reports
+// across multiple lines, starting each clause (FROM, WHERE, ORDER BY, ...)
+// on a new line.
+func WithPrettyPrint(enabled bool) Option {
+	return func(o *options) {
+		o.pretty = enabled
+	}
+}
+
+// formatOptions returns the options used to format differences, splitting
+// queries into tokens with n.
+func (o options) formatOptions(n *normalizer.Normalizer) comparator.FormatOptions {
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	if o.color != nil {
+		color = *o.color
+	}
+	return comparator.FormatOptions{
+		Color:    color,
+		Pretty:   o.pretty,
+		Tokenize: n.Tokens,
+	}
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // AssertOption configures assertion behavior.
 type AssertOption func(*assertOptions)
 
 // assertOptions holds assertion configuration.
 type assertOptions struct {
-	ignoreOrder bool
+	ignoreOrder      bool
+	ignoreArgs       bool
+	positionMatchers map[argPosition]ArgMatcher
+	columnMatchers   map[string]ArgMatcher
+	ignore           []ignoreRule
+	budgets          []queryBudget
+	detectNPlusOne   bool
+	accepted         []AcceptedDifference
+	acceptedFiles    []string
 }
 
 // defaultAssertOptions returns the default assertion options.
 func defaultAssertOptions() assertOptions {
 	return assertOptions{
 		ignoreOrder: false,
+		ignoreArgs:  false,
 	}
 }
 
@@ -109,3 +317,10 @@ func IgnoreOrder() AssertOption {
 		o.ignoreOrder = true
 	}
 }
+
+// IgnoreArgs makes the assertion ignore bind arguments.
+func IgnoreArgs() AssertOption {
+	return func(o *assertOptions) {
+		o.ignoreArgs = true
+	}
+}
@@ -0,0 +1,121 @@
+package migratiorm
+
+import (
+	"github.com/ucpr/migratiorm/internal/normalizer"
+)
+
+// Preset bundles the normalizations needed for the queries of an ORM or query builder.
+type Preset int
+
+// Preset constants.
+const (
+	PresetGORM Preset = iota
+	PresetSQLBoiler
+	PresetXO
+	PresetSQLx
+	PresetSQLC
+	PresetEnt
+	PresetBun
+)
+
+func (p Preset) String() string {
+	switch p {
+	case PresetGORM:
+		return "GORM"
+	case PresetSQLBoiler:
+		return "sqlboiler"
+	case PresetXO:
+		return "xo"
+	case PresetSQLx:
+		return "sqlx"
+	case PresetSQLC:
+		return "sqlc"
+	case PresetEnt:
+		return "ent"
+	case PresetBun:
+		return "bun"
+	default:
+		return "unknown"
+	}
+}
+
+// WithPreset enables the normalizations of the given presets, typically the
+// ORM being migrated from and the ORM being migrated to:
+//
+//	m := migratiorm.New(migratiorm.WithPreset(migratiorm.PresetSQLBoiler, migratiorm.PresetGORM))
+//
+// Every preset removes identifier quotes and comments, unifies placeholders,
+// uppercases keywords and spaces comparison operators. In addition:
+//   - GORM: SELECT * and table-qualified columns, First()'s ORDER BY pk LIMIT 1,
+//     deleted_at IS NULL soft deletes, Save() column order and RETURNING
+//   - sqlboiler: explicit table-qualified column lists, parenthesized
+//     predicates, One()'s LIMIT 1, soft deletes and RETURNING
+//   - xo: explicit table-qualified column lists
+//   - sqlx: hand-written JOIN and ORDER BY spellings
+//   - sqlc: explicit column lists and hand-written JOIN and ORDER BY spellings
+//   - ent: explicit table-qualified column lists, field order of INSERT and
+//     UPDATE, and RETURNING
+//   - bun: explicit table-qualified column lists, parenthesized predicates,
+//     soft deletes and RETURNING
+//
+// Soft-delete predicates are compared regardless of their position and
+// parentheses, but not removed, so a query that drops the deleted_at IS NULL
+// filter is still reported.
+//
+// Presets only enable normalizations, so options after WithPreset can turn them off again.
+func WithPreset(presets ...Preset) Option {
+	return func(o *options) {
+		for _, p := range presets {
+			p.apply(&o.normalizerOptions)
+		}
+	}
+}
+
+// apply enables the normalizer options of the preset.
+func (p Preset) apply(n *normalizer.Options) {
+	n.UnifyPlaceholders = true
+	n.RemoveComments = true
+	n.UppercaseKeywords = true
+	n.RemoveQuotes = true
+	n.SpaceOperators = true
+
+	switch p {
+	case PresetGORM:
+		n.NormalizeSelectColumns = true
+		n.NormalizeTableQualifiers = true
+		n.RemovePrimaryKeyLimit = true
+		n.NormalizeSoftDeletes = true
+		n.SortInsertColumns = true
+		n.SortUpdateColumns = true
+		n.RemoveReturningClause = true
+	case PresetSQLBoiler:
+		n.RemoveRedundantParens = true
+		n.NormalizeSelectColumns = true
+		n.NormalizeTableQualifiers = true
+		n.RemovePrimaryKeyLimit = true
+		n.NormalizeSoftDeletes = true
+		n.RemoveReturningClause = true
+	case PresetBun:
+		n.RemoveRedundantParens = true
+		n.NormalizeSelectColumns = true
+		n.NormalizeTableQualifiers = true
+		n.NormalizeSoftDeletes = true
+		n.RemoveReturningClause = true
+	case PresetXO:
+		n.NormalizeSelectColumns = true
+		n.NormalizeTableQualifiers = true
+	case PresetSQLx:
+		n.NormalizeJoinSyntax = true
+		n.NormalizeOrderByAsc = true
+	case PresetSQLC:
+		n.NormalizeSelectColumns = true
+		n.NormalizeJoinSyntax = true
+		n.NormalizeOrderByAsc = true
+	case PresetEnt:
+		n.NormalizeSelectColumns = true
+		n.NormalizeTableQualifiers = true
+		n.SortInsertColumns = true
+		n.SortUpdateColumns = true
+		n.RemoveReturningClause = true
+	}
+}
@@ -0,0 +1,28 @@
+package migratiorm
+
+import (
+	"database/sql/driver"
+)
+
+// WithDriver enables proxy mode on a real database opened by d with the data source name dsn.
+// Every statement is recorded exactly like in the capture database and then
+// forwarded, so real rows and errors flow back to the ORM. Expect and Actual
+// each open fresh connections to the same database.
+// Canned responses registered with WithResponse take precedence over forwarding.
+func WithDriver(d driver.Driver, dsn string) Option {
+	return func(o *options) {
+		o.connector = nil
+		o.driver = d
+		o.dsn = dsn
+	}
+}
+
+// WithConnector enables proxy mode on a real database opened by c.
+// It behaves like WithDriver. The connector is not closed by migratiorm.
+func WithConnector(c driver.Connector) Option {
+	return func(o *options) {
+		o.connector = c
+		o.driver = nil
+		o.dsn = ""
+	}
+}
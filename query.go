@@ -1,5 +1,9 @@
 package migratiorm
 
+import (
+	"github.com/ucpr/migratiorm/internal/ast"
+)
+
 // OperationType represents the type of SQL operation.
 type OperationType int
 
@@ -9,6 +13,9 @@ const (
 	OperationUpdate
 	OperationDelete
 	OperationOther
+	OperationBegin
+	OperationCommit
+	OperationRollback
 )
 
 func (o OperationType) String() string {
@@ -21,6 +28,12 @@ func (o OperationType) String() string {
 		return "UPDATE"
 	case OperationDelete:
 		return "DELETE"
+	case OperationBegin:
+		return "BEGIN"
+	case OperationCommit:
+		return "COMMIT"
+	case OperationRollback:
+		return "ROLLBACK"
 	default:
 		return "OTHER"
 	}
@@ -28,29 +41,21 @@ func (o OperationType) String() string {
 
 // Query represents a captured SQL query.
 type Query struct {
-	Raw        string        // Original query before normalization
-	Normalized string        // Query after normalization
-	Args       []any         // Bind parameters
-	Operation  OperationType // Type of operation (SELECT, INSERT, etc.)
+	Raw            string        // Original query before normalization
+	Normalized     string        // Query after normalization
+	Args           []any         // Bind parameters
+	NormalizedArgs []any         // Bind parameters in the placeholder order of Normalized
+	Operation      OperationType // Type of operation (SELECT, INSERT, etc.)
+	Statement      *Statement    // Parsed statement tree, nil for unsupported statements
 }
 
+// Statement is a parsed SELECT, INSERT, UPDATE or DELETE statement.
+// Its clauses are compared structurally when WithStructuralComparison is enabled.
+type Statement = ast.Statement
+
 // detectOperation detects the operation type from a SQL query.
 func detectOperation(query string) OperationType {
-	if len(query) < 6 {
-		return OperationOther
-	}
-
-	// Normalize first characters to uppercase for comparison
-	prefix := make([]byte, 6)
-	for i := 0; i < 6 && i < len(query); i++ {
-		c := query[i]
-		if c >= 'a' && c <= 'z' {
-			c -= 32 // Convert to uppercase
-		}
-		prefix[i] = c
-	}
-
-	switch string(prefix) {
+	switch firstKeyword(query) {
 	case "SELECT":
 		return OperationSelect
 	case "INSERT":
@@ -59,7 +64,34 @@ func detectOperation(query string) OperationType {
 		return OperationUpdate
 	case "DELETE":
 		return OperationDelete
+	case "BEGIN", "START":
+		return OperationBegin
+	case "COMMIT":
+		return OperationCommit
+	case "ROLLBACK":
+		return OperationRollback
 	default:
 		return OperationOther
 	}
 }
+
+// firstKeyword returns the first word of a SQL query in uppercase.
+func firstKeyword(query string) string {
+	start := 0
+	for start < len(query) && (query[start] == ' ' || query[start] == '\t' || query[start] == '\n' || query[start] == '\r') {
+		start++
+	}
+
+	keyword := make([]byte, 0, 8)
+	for i := start; i < len(query); i++ {
+		c := query[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 32 // Convert to uppercase
+		}
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		keyword = append(keyword, c)
+	}
+	return string(keyword)
+}
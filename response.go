@@ -0,0 +1,54 @@
+package migratiorm
+
+import (
+	"github.com/ucpr/migratiorm/internal/capturer"
+	"github.com/ucpr/migratiorm/internal/normalizer"
+)
+
+// Response is a canned result returned by the capture database.
+// Responses let ORMs read rows, so that flows which read a row and then
+// issue follow-up queries are captured completely.
+type Response struct {
+	Query        string             // Query text to match after normalization
+	Match        func(q Query) bool // Custom predicate; takes precedence over Query
+	Columns      []string           // Column names of the result set
+	Rows         [][]any            // Row values, one slice per row in Columns order
+	RowsAffected int64              // Value returned by sql.Result.RowsAffected
+	LastInsertID int64              // Value returned by sql.Result.LastInsertId
+}
+
+// WithResponse registers a canned response for matching queries.
+// Responses are applied identically to the Expect and Actual capture databases.
+// When several responses match a query, the first registered one wins.
+// Queries without a matching response return no rows.
+func WithResponse(r Response) Option {
+	return func(o *options) {
+		o.responses = append(o.responses, r)
+	}
+}
+
+// captureResponses converts the registered responses for a capturer whose
+// queries are normalized with n.
+func (m *Migratiorm) captureResponses(n *normalizer.Normalizer) []capturer.Response {
+	result := make([]capturer.Response, len(m.options.responses))
+	for i, r := range m.options.responses {
+		match := r.Match
+		if match == nil {
+			normalized := n.Normalize(r.Query)
+			match = func(q Query) bool {
+				return q.Normalized == normalized
+			}
+		}
+
+		result[i] = capturer.Response{
+			Match: func(query string, args []any) bool {
+				return match(buildQuery(n, query, args))
+			},
+			Columns:      r.Columns,
+			Rows:         r.Rows,
+			RowsAffected: r.RowsAffected,
+			LastInsertID: r.LastInsertID,
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package migratiorm
+
+import (
+	"github.com/ucpr/migratiorm/internal/normalizer"
+)
+
+// Stage is a position in the normalization pipeline where rewrite rules run.
+type Stage = normalizer.Stage
+
+// Stage constants.
+const (
+	// StageRaw runs on the raw query as captured, before any normalization.
+	StageRaw = normalizer.StageRaw
+	// StageLexical runs after comments, quotes, placeholders and keyword case
+	// are normalized, before semantic normalizations such as sorting columns.
+	StageLexical = normalizer.StageLexical
+	// StageFinal runs on the normalized query.
+	StageFinal = normalizer.StageFinal
+)
+
+// Side selects the queries a rewrite rule applies to.
+type Side int
+
+const (
+	// BothSides applies to the expected and actual queries.
+	BothSides Side = iota
+	// ExpectedSide applies to the queries captured by Expect and stored in golden files.
+	ExpectedSide
+	// ActualSide applies to the queries captured by Actual.
+	ActualSide
+)
+
+// rewriteRule is a rewrite rule registered with WithRewrite.
+type rewriteRule struct {
+	stage   Stage
+	side    Side
+	rewrite func(query string) string
+}
+
+// WithRewrite registers a rewrite rule that runs at the given stage of the
+// normalization pipeline, on the queries of the given side. Rules of the same
+// stage run in registration order. For example, a table renamed as part of the
+// migration is compared under its new name with
+//
+//	migratiorm.WithRewrite(migratiorm.StageLexical, migratiorm.ExpectedSide, func(q string) string {
+//		return strings.ReplaceAll(q, " accounts ", " users ")
+//	})
+//
+// Bind arguments follow the placeholders of the rewritten query in order.
+func WithRewrite(stage Stage, side Side, rewrite func(query string) string) Option {
+	return func(o *options) {
+		o.rewrites = append(o.rewrites, rewriteRule{stage: stage, side: side, rewrite: rewrite})
+	}
+}
+
+// sideNormalizerOptions returns the normalizer options with the rewrite rules of a side.
+func (o options) sideNormalizerOptions(side Side) normalizer.Options {
+	opts := o.normalizerOptions
+	opts.Rewrites = nil
+	for _, r := range o.rewrites {
+		if r.side == BothSides || r.side == side {
+			opts.Rewrites = append(opts.Rewrites, normalizer.Rewrite{Stage: r.stage, Apply: r.rewrite})
+		}
+	}
+	return opts
+}